@@ -8,28 +8,38 @@ import (
 	"strings"
 
 	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
-	networkingv1 "istio.io/api/networking/v1"
-	networkingv1beta1 "istio.io/api/networking/v1beta1"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	networkingapiv1 "istio.io/api/networking/v1"
 	v1 "istio.io/client-go/pkg/apis/networking/v1"
 	"istio.io/client-go/pkg/apis/networking/v1beta1"
 	istioversionedclient "istio.io/client-go/pkg/clientset/versioned"
 	corev1 "k8s.io/api/core/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	k8scache "k8s.io/client-go/tools/cache"
+	gatewayapiclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+
+	v1certmanagermeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	certmanagerinformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
-)
-
-const (
-	secretNameMaxLength = 253
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 )
 
 type GatewayMutationHook struct {
 	istioClient istioversionedclient.Interface
-	nsLister    corev1listers.NamespaceLister
-	decoder     admission.Decoder
-	externalDNS *ExternalDNSConfig
+	// managed Certificates indexed by name, see WithCertificateClient
+	certInformerFactory certmanagerinformers.SharedInformerFactory
+	certificates        k8scache.SharedIndexInformer
+	gatewayAPIClient    gatewayapiclient.Interface
+	nsLister            corev1listers.NamespaceLister
+	decoder             admission.Decoder
+	externalDNS         *ExternalDNSConfig
+	maxSANs             int
+	granularity         naming.Granularity
+	clusterIssuer       string
 }
 
 // ExternalDNSConfig passes configuration to the external DNS mutation behavior
@@ -79,8 +89,10 @@ func NewExternalDNSConfig() *ExternalDNSConfig {
 
 func NewGatewayMutationHook(client istioversionedclient.Interface, nsl corev1listers.NamespaceLister, opts ...OptionsFunc) *GatewayMutationHook {
 	gmh := &GatewayMutationHook{
-		istioClient: client,
-		nsLister:    nsl,
+		istioClient:   client,
+		nsLister:      nsl,
+		granularity:   naming.GranularityServer,
+		clusterIssuer: "default",
 	}
 
 	for _, opt := range opts {
@@ -90,12 +102,27 @@ func NewGatewayMutationHook(client istioversionedclient.Interface, nsl corev1lis
 	return gmh
 }
 
-func (g *GatewayMutationHook) SetupWithManager(mgr manager.Manager) {
+func (g *GatewayMutationHook) SetupWithManager(mgr manager.Manager) error {
 	g.InjectDecoder(admission.NewDecoder(mgr.GetScheme()))
 	mgr.GetWebhookServer().Register("/mutate", &webhook.Admission{Handler: g})
+
+	if g.certInformerFactory == nil {
+		return nil
+	}
+
+	// the Certificate cache runs with the manager, conflicts are not checked until it has synced
+	return mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		g.certInformerFactory.Start(ctx.Done())
+		<-ctx.Done()
+		return nil
+	}))
 }
 
 func (g *GatewayMutationHook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Kind.Group == gatewayapiv1beta1.GroupName {
+		return g.handleGatewayAPI(ctx, req)
+	}
+
 	switch req.Kind.Version {
 	case "v1":
 		return g.handleV1(ctx, req)
@@ -118,14 +145,23 @@ func (g *GatewayMutationHook) handleV1Beta1(ctx context.Context, req admission.R
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	// the namespace selects the external DNS behavior and the default issuer of shared credentialNames
 	var ns *corev1.Namespace
-	if g.externalDNS != nil && g.externalDNS.enabled {
+	if g.nsLister != nil {
 		ns, err = g.nsLister.Get(gateway.Namespace)
 		if err != nil {
 			log.Error(err, fmt.Sprintf("failed to get namespace: %s", gateway.Namespace))
 		}
 	}
-	gateway = mutateV1Beta1(ctx, gateway.DeepCopy(), g.externalDNS, ns)
+	gateway = mutateV1Beta1(ctx, gateway.DeepCopy(), g.externalDNS, ns, g.granularity, g.clusterIssuer)
+
+	names := serverCredentialNames(gateway, gateway.Spec.Servers)
+	old := &v1beta1.Gateway{}
+	if !g.credentialNamesUnchanged(req, names, old, func() []string { return serverCredentialNames(old, old.Spec.Servers) }) {
+		if conflicts := g.credentialNameConflicts(ctx, gateway, names); len(conflicts) > 0 {
+			return admission.Denied(strings.Join(conflicts, "; "))
+		}
+	}
 
 	jsonGateway, err := json.Marshal(gateway)
 	if err != nil {
@@ -133,7 +169,9 @@ func (g *GatewayMutationHook) handleV1Beta1(ctx context.Context, req admission.R
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	return admission.PatchResponseFromRaw(req.Object.Raw, jsonGateway)
+	response := admission.PatchResponseFromRaw(req.Object.Raw, jsonGateway)
+	response.Warnings = append(maxSANsWarnings(gateway.Labels, gateway.Spec.Servers, g.maxSANs), hostGranularityWarnings(gateway, gateway.Spec.Servers, g.granularity)...)
+	return response
 }
 
 func (g *GatewayMutationHook) handleV1(ctx context.Context, req admission.Request) admission.Response {
@@ -147,14 +185,60 @@ func (g *GatewayMutationHook) handleV1(ctx context.Context, req admission.Reques
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
+	// the namespace selects the external DNS behavior and the default issuer of shared credentialNames
 	var ns *corev1.Namespace
-	if g.externalDNS != nil && g.externalDNS.enabled {
+	if g.nsLister != nil {
 		ns, err = g.nsLister.Get(gateway.Namespace)
 		if err != nil {
 			log.Error(err, fmt.Sprintf("failed to get namespace: %s", gateway.Namespace))
 		}
 	}
-	gateway = mutateV1(ctx, gateway.DeepCopy(), g.externalDNS, ns)
+	gateway = mutateV1(ctx, gateway.DeepCopy(), g.externalDNS, ns, g.granularity, g.clusterIssuer)
+
+	names := serverCredentialNames(gateway, gateway.Spec.Servers)
+	old := &v1.Gateway{}
+	if !g.credentialNamesUnchanged(req, names, old, func() []string { return serverCredentialNames(old, old.Spec.Servers) }) {
+		if conflicts := g.credentialNameConflicts(ctx, gateway, names); len(conflicts) > 0 {
+			return admission.Denied(strings.Join(conflicts, "; "))
+		}
+	}
+
+	jsonGateway, err := json.Marshal(gateway)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("failed to marshal gateway: %s", gateway.Name))
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	response := admission.PatchResponseFromRaw(req.Object.Raw, jsonGateway)
+	response.Warnings = append(maxSANsWarnings(gateway.Labels, gateway.Spec.Servers, g.maxSANs), hostGranularityWarnings(gateway, gateway.Spec.Servers, g.granularity)...)
+	return response
+}
+
+func (g *GatewayMutationHook) handleGatewayAPI(ctx context.Context, req admission.Request) admission.Response {
+	log := log.FromContext(ctx)
+
+	if req.Kind.Version != gatewayapiv1beta1.SchemeGroupVersion.Version {
+		return admission.Errored(http.StatusBadRequest,
+			fmt.Errorf("unsupported Gateway API version: %s", req.Kind.Version))
+	}
+
+	gateway := &gatewayapiv1beta1.Gateway{}
+
+	err := g.decoder.Decode(req, gateway)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("failed to decode gateway request: %s", req.Name))
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	gateway = mutateGatewayAPIV1Beta1(ctx, gateway.DeepCopy(), g.granularity)
+
+	names := listenerCertificateNames(gateway)
+	old := &gatewayapiv1beta1.Gateway{}
+	if !g.credentialNamesUnchanged(req, names, old, func() []string { return listenerCertificateNames(old) }) {
+		if conflicts := g.credentialNameConflicts(ctx, gateway, names); len(conflicts) > 0 {
+			return admission.Denied(strings.Join(conflicts, "; "))
+		}
+	}
 
 	jsonGateway, err := json.Marshal(gateway)
 	if err != nil {
@@ -173,18 +257,109 @@ func credentialName(ctx context.Context, namespace, name string, portName string
 	log := log.FromContext(ctx)
 	prefix := fmt.Sprintf("%s-%s", namespace, name)
 	// Leave enough space for dash before the portName suffix.
-	maxPrefixLen := secretNameMaxLength - len(portName) - 1
+	maxPrefixLen := naming.CredentialNameMaxLength - len(portName) - 1
 
 	if len(prefix) > maxPrefixLen {
-		prefix = prefix[:maxPrefixLen]
+		prefix = naming.TruncateWithHash(prefix, maxPrefixLen, namespace+"/"+name)
 		log.Info(fmt.Sprintf("truncating gateway %s credentialName to %s", name, prefix))
 	}
 
 	return fmt.Sprintf("%s-%s", prefix, portName)
 }
 
-func mutateV1Beta1(ctx context.Context, gateway *v1beta1.Gateway, externalDNS *ExternalDNSConfig, ns *corev1.Namespace) *v1beta1.Gateway {
+// serverCredentialName names the credential of a server, or listener, after the Gateway, its single host, its
+// hosts or its port name, depending on the granularity requested by the Gateway or the default granularity.  With
+// the host granularity, servers with more than one host, or a host that cannot be issued, are named after the
+// port.  With the shared granularity, only shareable servers are named after their hosts and the resolved issuer.
+func serverCredentialName(ctx context.Context, gateway client.Object, granularity naming.Granularity, portName string, hosts []string, issuer v1certmanagermeta.ObjectReference, shareable bool) string {
+	switch naming.GranularityFor(gateway, granularity) {
+	case naming.GranularityShared:
+		if shareable {
+			if name, ok := naming.SharedCredentialName(hosts, issuer); ok {
+				return name
+			}
+		}
+	case naming.GranularityGateway:
+		return gatewayCredentialName(ctx, gateway.GetNamespace(), gateway.GetName())
+	case naming.GranularityHost:
+		if len(hosts) == 1 {
+			if suffix, ok := naming.HostCredentialSuffix(hosts[0]); ok {
+				return credentialName(ctx, gateway.GetNamespace(), gateway.GetName(), suffix)
+			}
+		}
+	}
+
+	return credentialName(ctx, gateway.GetNamespace(), gateway.GetName(), portName)
+}
+
+func gatewayCredentialName(ctx context.Context, namespace, name string) string {
+	log := log.FromContext(ctx)
+	n := fmt.Sprintf("%s-%s", namespace, name)
+
+	if len(n) > naming.CredentialNameMaxLength {
+		n = naming.TruncateWithHash(n, naming.CredentialNameMaxLength, namespace+"/"+name)
+		log.Info(fmt.Sprintf("truncating gateway %s credentialName to %s", name, n))
+	}
+
+	return n
+}
+
+// hostGranularityWarnings warns about managed servers that cannot be given a Certificate per host, because
+// Istio serves a single credential per server.
+func hostGranularityWarnings(gateway client.Object, servers []*networkingapiv1.Server, granularity naming.Granularity) []string {
+	if val, ok := gateway.GetLabels()[v1beta1labels.InjectSimpleCredentialNameLabel]; !ok || val != "true" {
+		return nil
+	}
+
+	if naming.GranularityFor(gateway, granularity) != naming.GranularityHost {
+		return nil
+	}
+
+	warnings := []string{}
+	for _, s := range servers {
+		if s.Tls == nil || !naming.IsManagedTLSMode(s.Tls.Mode) || len(s.Hosts) == 1 || naming.IsUnmanagedServer(gateway, s.Port.GetName(), s.Hosts) {
+			continue
+		}
+
+		warnings = append(warnings, fmt.Sprintf("server %s has %d hosts: Istio serves a single Certificate per server, so it is given one Certificate for all of its hosts, split the server to get a Certificate per host", s.Port.GetName(), len(s.Hosts)))
+	}
+
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	return warnings
+}
+
+// maxSANsWarnings warns about managed servers with more hosts than fit in a single Certificate.  The controller
+// splits their hosts across several Certificates but Istio only serves the credentialName of a server, so the
+// remaining hosts are not served.
+func maxSANsWarnings(labels map[string]string, servers []*networkingapiv1.Server, max int) []string {
+	if val, ok := labels[v1beta1labels.InjectSimpleCredentialNameLabel]; !ok || val != "true" || max <= 0 {
+		return nil
+	}
+
+	warnings := []string{}
+	for _, s := range servers {
+		if s.Tls == nil || !naming.IsManagedTLSMode(s.Tls.Mode) {
+			continue
+		}
+
+		if n := naming.CertificateNameCount(s.Hosts); n > max {
+			warnings = append(warnings, fmt.Sprintf("server %s has %d hosts, more than the maximum of %d per Certificate: the hosts are split across several Certificates and Istio only serves %s, move the remaining hosts to another server", s.Port.GetName(), n, max, s.Tls.CredentialName))
+		}
+	}
+
+	if len(warnings) == 0 {
+		return nil
+	}
+
+	return warnings
+}
+
+func mutateV1Beta1(ctx context.Context, gateway *v1beta1.Gateway, externalDNS *ExternalDNSConfig, ns *corev1.Namespace, granularity naming.Granularity, clusterIssuer string) *v1beta1.Gateway {
 	log := log.FromContext(ctx)
+	issuer, _ := naming.ResolveIssuer(clusterIssuer, ns, gateway)
 
 	if externalDNS != nil && externalDNS.enabled {
 		externalDNS.mutateV1Beta1(ctx, gateway, ns)
@@ -197,8 +372,13 @@ func mutateV1Beta1(ctx context.Context, gateway *v1beta1.Gateway, externalDNS *E
 				continue
 			}
 
-			if s.Tls.Mode == networkingv1beta1.ServerTLSSettings_SIMPLE {
-				newCredentialName := credentialName(ctx, gateway.Namespace, gateway.Name, s.Port.Name)
+			if naming.IsUnmanagedServer(gateway, s.Port.GetName(), s.Hosts) {
+				log.Info(fmt.Sprintf("keeping gateway %s unmanaged server %s Tls.CredentialName %s", gateway.Name, s.Port.GetName(), s.Tls.CredentialName))
+				continue
+			}
+
+			if naming.IsManagedTLSMode(s.Tls.Mode) {
+				newCredentialName := serverCredentialName(ctx, gateway, granularity, s.Port.Name, s.Hosts, issuer, s.Tls.Mode == networkingapiv1.ServerTLSSettings_SIMPLE)
 				log.Info(fmt.Sprintf("mutating gateway %s Tls.CredentialName, %s to %s", gateway.Name, s.Tls.CredentialName, newCredentialName))
 				s.Tls.CredentialName = newCredentialName
 			}
@@ -208,8 +388,9 @@ func mutateV1Beta1(ctx context.Context, gateway *v1beta1.Gateway, externalDNS *E
 	return gateway
 }
 
-func mutateV1(ctx context.Context, gateway *v1.Gateway, externalDNS *ExternalDNSConfig, ns *corev1.Namespace) *v1.Gateway {
+func mutateV1(ctx context.Context, gateway *v1.Gateway, externalDNS *ExternalDNSConfig, ns *corev1.Namespace, granularity naming.Granularity, clusterIssuer string) *v1.Gateway {
 	log := log.FromContext(ctx)
+	issuer, _ := naming.ResolveIssuer(clusterIssuer, ns, gateway)
 
 	if externalDNS != nil && externalDNS.enabled {
 		externalDNS.mutateV1(ctx, gateway, ns)
@@ -222,8 +403,13 @@ func mutateV1(ctx context.Context, gateway *v1.Gateway, externalDNS *ExternalDNS
 				continue
 			}
 
-			if s.Tls.Mode == networkingv1.ServerTLSSettings_SIMPLE {
-				newCredentialName := credentialName(ctx, gateway.Namespace, gateway.Name, s.Port.Name)
+			if naming.IsUnmanagedServer(gateway, s.Port.GetName(), s.Hosts) {
+				log.Info(fmt.Sprintf("keeping gateway %s unmanaged server %s Tls.CredentialName %s", gateway.Name, s.Port.GetName(), s.Tls.CredentialName))
+				continue
+			}
+
+			if naming.IsManagedTLSMode(s.Tls.Mode) {
+				newCredentialName := serverCredentialName(ctx, gateway, granularity, s.Port.Name, s.Hosts, issuer, s.Tls.Mode == networkingapiv1.ServerTLSSettings_SIMPLE)
 				log.Info(fmt.Sprintf("mutating gateway %s Tls.CredentialName, %s to %s", gateway.Name, s.Tls.CredentialName, newCredentialName))
 				s.Tls.CredentialName = newCredentialName
 			}
@@ -233,6 +419,53 @@ func mutateV1(ctx context.Context, gateway *v1.Gateway, externalDNS *ExternalDNS
 	return gateway
 }
 
+// mutateGatewayAPIV1Beta1 points the certificateRefs of each terminating TLS listener at a Secret named after the
+// listener, the Gateway or the listener hostname depending on the granularity, the Gateway API equivalent of the
+// Istio Tls.CredentialName mutation.
+func mutateGatewayAPIV1Beta1(ctx context.Context, gateway *gatewayapiv1beta1.Gateway, granularity naming.Granularity) *gatewayapiv1beta1.Gateway {
+	log := log.FromContext(ctx)
+
+	// If we don't have the tls management label or it isn't set to true return
+	if val, ok := gateway.Labels[v1beta1labels.InjectSimpleCredentialNameLabel]; !ok || val != "true" {
+		return gateway
+	}
+
+	for i := range gateway.Spec.Listeners {
+		l := &gateway.Spec.Listeners[i]
+		if l.TLS == nil {
+			continue
+		}
+
+		if l.TLS.Mode != nil && *l.TLS.Mode != gatewayapiv1beta1.TLSModeTerminate {
+			continue
+		}
+
+		if naming.IsUnmanagedListener(gateway, *l) {
+			log.Info(fmt.Sprintf("keeping gateway %s unmanaged listener %s certificateRefs", gateway.Name, l.Name))
+			continue
+		}
+
+		group := gatewayapiv1beta1.Group("")
+		kind := gatewayapiv1beta1.Kind("Secret")
+		hosts := []string{}
+		if l.Hostname != nil && *l.Hostname != "" {
+			hosts = append(hosts, string(*l.Hostname))
+		}
+
+		newCredentialName := serverCredentialName(ctx, gateway, granularity, string(l.Name), hosts, v1certmanagermeta.ObjectReference{}, false)
+		log.Info(fmt.Sprintf("mutating gateway %s listener %s certificateRefs to %s", gateway.Name, l.Name, newCredentialName))
+		l.TLS.CertificateRefs = []gatewayapiv1beta1.SecretObjectReference{
+			{
+				Group: &group,
+				Kind:  &kind,
+				Name:  gatewayapiv1beta1.ObjectName(newCredentialName),
+			},
+		}
+	}
+
+	return gateway
+}
+
 func (edc *ExternalDNSConfig) mutateV1Beta1(ctx context.Context, gateway *v1beta1.Gateway, ns *corev1.Namespace) {
 	// If we don't have information about the namespace assume we want to mutate it.
 	if ns != nil {
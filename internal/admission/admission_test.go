@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
 	"github.com/stretchr/testify/assert"
 	networkingapiv1 "istio.io/api/networking/v1"
 	networkingv1beta1 "istio.io/api/networking/v1beta1"
@@ -22,6 +23,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	v1certmanagermeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	certmanagerfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
 )
 
 type fakeNSLister struct {
@@ -279,6 +285,172 @@ func TestGatewayMutationHookVersionRouting(t *testing.T) {
 	}
 }
 
+func TestGatewayMutationHookMaxSANsWarnings(t *testing.T) {
+	t.Parallel()
+
+	gmh := NewGatewayMutationHook(istiofake.NewSimpleClientset(), &fakeNSLister{}, WithMaxSANsPerCertificate(2))
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(networkingv1.SchemeBuilder.AddToScheme(scheme))
+	gmh.InjectDecoder(admission.NewDecoder(scheme))
+
+	gateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-gateway",
+			Namespace: "devops",
+			Labels:    map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		},
+		Spec: networkingapiv1.Gateway{
+			Servers: []*networkingapiv1.Server{
+				{
+					Hosts: []string{"a.example.com", "b.example.com"},
+					Port:  &networkingapiv1.Port{Number: 443, Name: "https"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE},
+				},
+				{
+					Hosts: []string{"a.example.com", "b.example.com", "c.example.com"},
+					Port:  &networkingapiv1.Port{Number: 8443, Name: "https-alt"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE},
+				},
+				{
+					Hosts: []string{"a.example.com", "b.example.com", "c.example.com"},
+					Port:  &networkingapiv1.Port{Number: 9443, Name: "passthrough"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_PASSTHROUGH},
+				},
+			},
+		},
+	}
+
+	gatewayBytes, err := json.Marshal(gateway)
+	assert.NoError(t, err)
+
+	response := gmh.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Kind:   metav1.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "Gateway"},
+		Object: runtime.RawExtension{Raw: gatewayBytes},
+	}})
+	assert.True(t, response.Allowed)
+	assert.Len(t, response.Warnings, 1)
+	assert.Contains(t, response.Warnings[0], "server https-alt has 3 hosts")
+	assert.Contains(t, response.Warnings[0], "devops-test-gateway-https-alt")
+
+	// unmanaged Gateways and an unlimited maximum are not warned about
+	assert.Nil(t, maxSANsWarnings(nil, gateway.Spec.Servers, 2))
+	assert.Nil(t, maxSANsWarnings(gateway.Labels, gateway.Spec.Servers, 0))
+}
+
+func TestGatewayMutationHookCredentialNameConflicts(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(networkingv1.SchemeBuilder.AddToScheme(scheme))
+
+	// devops-team/web and devops/team-web are both given devops-team-web-https
+	gateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "devops-team",
+			Labels:    map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		},
+		Spec: networkingapiv1.Gateway{
+			Servers: []*networkingapiv1.Server{{
+				Hosts: []string{"a.example.com"},
+				Port:  &networkingapiv1.Port{Number: 443, Name: "https"},
+				Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE},
+			}},
+		},
+	}
+	other := &networkingv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "team-web", Namespace: "devops"}}
+
+	newCertificate := func(owner string, annotations map[string]string) *v1certmanager.Certificate {
+		return &v1certmanager.Certificate{ObjectMeta: metav1.ObjectMeta{
+			Name:        "devops-team-web-https",
+			Namespace:   "istio-system",
+			Labels:      map[string]string{v1beta1labels.ManagedLabel: owner},
+			Annotations: annotations,
+		}}
+	}
+
+	tests := []struct {
+		description string
+		cert        *v1certmanager.Certificate
+		gateways    []*networkingv1.Gateway
+		wantAllowed bool
+	}{
+		{description: "Certificate of another Gateway", cert: newCertificate("team-web.devops", nil), gateways: []*networkingv1.Gateway{other}},
+		{description: "Certificate of a deleted Gateway", cert: newCertificate("team-web.devops", nil), wantAllowed: true},
+		{description: "Certificate of the Gateway", cert: newCertificate("web.devops-team", nil), gateways: []*networkingv1.Gateway{other}, wantAllowed: true},
+		{
+			description: "shared Certificate",
+			cert:        newCertificate("team-web.devops", map[string]string{v1beta1labels.ReferencedByAnnotation: "team-web.devops"}),
+			gateways:    []*networkingv1.Gateway{other},
+			wantAllowed: true,
+		},
+	}
+
+	gatewayBytes, err := json.Marshal(gateway)
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		istioClient := istiofake.NewSimpleClientset()
+		for _, gw := range test.gateways {
+			_, err := istioClient.NetworkingV1().Gateways(gw.Namespace).Create(context.TODO(), gw, metav1.CreateOptions{})
+			assert.NoError(t, err, test.description)
+		}
+
+		gmh := newTestHookWithCertificates(t, istioClient, test.cert)
+		gmh.InjectDecoder(admission.NewDecoder(scheme))
+
+		response := gmh.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:   metav1.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "Gateway"},
+			Object: runtime.RawExtension{Raw: gatewayBytes},
+		}})
+		assert.Equal(t, test.wantAllowed, response.Allowed, test.description)
+		if !test.wantAllowed {
+			assert.Contains(t, response.Result.Message, "credentialName devops-team-web-https is already used by Certificate istio-system/devops-team-web-https of Gateway devops/team-web", test.description)
+		}
+	}
+
+	// updates keeping the credentialNames of the old object, e.g. status patches, are not checked again
+	istioClient := istiofake.NewSimpleClientset()
+	_, err = istioClient.NetworkingV1().Gateways("devops").Create(context.TODO(), &networkingv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "team-web", Namespace: "devops"}}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	gmh := newTestHookWithCertificates(t, istioClient, newCertificate("team-web.devops", nil))
+	gmh.InjectDecoder(admission.NewDecoder(scheme))
+
+	mutated := mutateV1(context.TODO(), gateway.DeepCopy(), nil, nil, naming.GranularityServer, "default")
+	mutatedBytes, err := json.Marshal(mutated)
+	assert.NoError(t, err)
+
+	request := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "Gateway"},
+		Operation: admissionv1.Update,
+		Object:    runtime.RawExtension{Raw: gatewayBytes},
+		OldObject: runtime.RawExtension{Raw: mutatedBytes},
+	}}
+	assert.True(t, gmh.Handle(context.TODO(), request).Allowed)
+
+	// a changed credentialName is checked
+	request.OldObject = runtime.RawExtension{Raw: gatewayBytes}
+	assert.False(t, gmh.Handle(context.TODO(), request).Allowed)
+
+	// conflicts are not checked before the cache has synced
+	unsynced := NewGatewayMutationHook(istioClient, &fakeNSLister{}, WithCertificateClient(certmanagerfake.NewSimpleClientset(newCertificate("team-web.devops", nil))))
+	assert.Empty(t, unsynced.credentialNameConflicts(context.TODO(), mutated, []string{"devops-team-web-https"}))
+}
+
+// newTestHookWithCertificates returns a GatewayMutationHook checking conflicts against a synced cache of the
+// Certificates.
+func newTestHookWithCertificates(t *testing.T, istioClient *istiofake.Clientset, certs ...runtime.Object) *GatewayMutationHook {
+	gmh := NewGatewayMutationHook(istioClient, &fakeNSLister{}, WithCertificateClient(certmanagerfake.NewSimpleClientset(certs...)))
+
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	gmh.certInformerFactory.Start(stop)
+	gmh.certInformerFactory.WaitForCacheSync(stop)
+
+	return gmh
+}
+
 func TestCredentialName(t *testing.T) {
 	t.Parallel()
 	const portName = "https"
@@ -300,18 +472,35 @@ func TestCredentialName(t *testing.T) {
 			description: "generated credentialName is truncated",
 			namespace:   strings.Repeat("a", 125),
 			name:        strings.Repeat("b", 125),
-			// some characters from the end of name should be truncated
-			want:    fmt.Sprintf("%s-%s-%s", strings.Repeat("a", 125), strings.Repeat("b", 121), portName),
-			wantLen: secretNameMaxLength,
+			// the end of name is replaced with a hash of the Gateway
+			want:    fmt.Sprintf("%s-%s-61968a89-%s", strings.Repeat("a", 125), strings.Repeat("b", 105), portName),
+			wantLen: naming.CredentialNameMaxLength,
 		},
 	}
 
 	for _, test := range tests {
 		n := credentialName(context.TODO(), test.namespace, test.name, portName)
 
-		assert.Equal(t, n, test.want, test.description)
+		assert.Equal(t, test.want, n, test.description)
 		assert.Equal(t, test.wantLen, len(n), test.description)
 	}
+
+	// the client CA Secret of a mutual TLS server named at the maximum length is still a valid Secret name
+	for _, n := range []string{
+		credentialName(context.TODO(), strings.Repeat("a", 125), strings.Repeat("b", 125), portName),
+		gatewayCredentialName(context.TODO(), strings.Repeat("a", 130), strings.Repeat("b", 130)),
+	} {
+		assert.Len(t, n, naming.CredentialNameMaxLength)
+		assert.LessOrEqual(t, len(n+naming.ClientCASecretSuffix), naming.SecretNameMaxLength)
+	}
+
+	// Gateways only differing beyond the limit are given different names
+	assert.NotEqual(t,
+		credentialName(context.TODO(), strings.Repeat("a", 125), strings.Repeat("b", 125), portName),
+		credentialName(context.TODO(), strings.Repeat("a", 125), strings.Repeat("b", 124)+"c", portName))
+	assert.NotEqual(t,
+		gatewayCredentialName(context.TODO(), strings.Repeat("a", 130), strings.Repeat("b", 130)),
+		gatewayCredentialName(context.TODO(), strings.Repeat("a", 130), strings.Repeat("b", 129)+"c"))
 }
 
 func TestMutateV1Beta1(t *testing.T) {
@@ -371,7 +560,7 @@ func TestMutateV1Beta1(t *testing.T) {
 		},
 	}
 
-	mutatedGateway := mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway := mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 
 	assert.Equal(t, gateway.Spec.Servers[0], mutatedGateway.Spec.Servers[0])
 	assert.Equal(t, gateway.Spec.Servers[1], mutatedGateway.Spec.Servers[1])
@@ -391,7 +580,7 @@ func TestMutateV1Beta1(t *testing.T) {
 		},
 	}
 
-	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	assert.Equal(t, "more,hosts", mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey])
 	assert.Equal(t, "there", gateway.Annotations[v1beta1labels.ExternalDNSTargetAnnotationKey])
@@ -405,7 +594,7 @@ func TestMutateV1Beta1(t *testing.T) {
 	}
 
 	gateway.Labels = map[string]string{}
-	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	_, found = mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey]
 	assert.False(t, found)
@@ -416,7 +605,7 @@ func TestMutateV1Beta1(t *testing.T) {
 	eDNS = NewExternalDNSConfig()
 	eDNS.SetEnabled(true)
 
-	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	_, found = mutatedGateway.Annotations[v1beta1labels.ExternalDNSTargetAnnotationKey]
 	assert.False(t, found)
@@ -433,7 +622,7 @@ func TestMutateV1Beta1(t *testing.T) {
 	eDNS.SetTarget("vanity-target")
 	assert.NoError(t, eDNS.SetSelector("testkey=testvalue"))
 
-	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	assert.Equal(t, "more,hosts", mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey])
 	assert.Equal(t, "there", gateway.Annotations[v1beta1labels.ExternalDNSTargetAnnotationKey])
@@ -445,7 +634,7 @@ func TestMutateV1Beta1(t *testing.T) {
 			Name:        "devops",
 		},
 	}
-	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	_, found = mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey]
 	assert.False(t, found)
@@ -453,7 +642,7 @@ func TestMutateV1Beta1(t *testing.T) {
 
 	// Ensure we do mutate external dns annotations when passed a nil namespace pointer
 	var nilNS *corev1.Namespace
-	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, nilNS)
+	mutatedGateway = mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, nilNS, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	_, found = mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey]
 	assert.False(t, found)
@@ -502,6 +691,16 @@ func TestMutateV1(t *testing.T) {
 						CredentialName: "should-be-mutated",
 					},
 				},
+				{
+					Port: &networkingapiv1.Port{
+						Number: 8443,
+						Name:   "mtls",
+					},
+					Tls: &networkingapiv1.ServerTLSSettings{
+						Mode:           networkingapiv1.ServerTLSSettings_MUTUAL,
+						CredentialName: "should-be-mutated",
+					},
+				},
 			},
 		},
 	}
@@ -517,7 +716,8 @@ func TestMutateV1(t *testing.T) {
 		},
 	}
 
-	mutatedGateway := mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway := mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
+	assert.Equal(t, "devops-example-gateway-mtls", mutatedGateway.Spec.Servers[3].Tls.CredentialName)
 
 	assert.Equal(t, gateway.Spec.Servers[0], mutatedGateway.Spec.Servers[0])
 	assert.Equal(t, gateway.Spec.Servers[1], mutatedGateway.Spec.Servers[1])
@@ -537,7 +737,7 @@ func TestMutateV1(t *testing.T) {
 		},
 	}
 
-	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	assert.Equal(t, "more,hosts", mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey])
 	assert.Equal(t, "there", gateway.Annotations[v1beta1labels.ExternalDNSTargetAnnotationKey])
@@ -551,7 +751,7 @@ func TestMutateV1(t *testing.T) {
 	}
 
 	gateway.Labels = map[string]string{}
-	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	_, found = mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey]
 	assert.False(t, found)
@@ -562,7 +762,7 @@ func TestMutateV1(t *testing.T) {
 	eDNS = NewExternalDNSConfig()
 	eDNS.SetEnabled(true)
 
-	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	_, found = mutatedGateway.Annotations[v1beta1labels.ExternalDNSTargetAnnotationKey]
 	assert.False(t, found)
@@ -573,7 +773,7 @@ func TestMutateV1(t *testing.T) {
 	eDNS.SetTarget("vanity-target")
 
 	var nilNS *corev1.Namespace
-	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, nilNS)
+	mutatedGateway = mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, nilNS, naming.GranularityServer, "default")
 	assert.NotNil(t, mutatedGateway.Annotations)
 	_, found = mutatedGateway.Annotations[v1beta1labels.ExternalDNSHostnameAnnotationKey]
 	assert.False(t, found)
@@ -613,7 +813,7 @@ func TestMutateV1Beta1WithNoMetadata(t *testing.T) {
 		},
 	}
 
-	mutatedGateway := mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway := mutateV1Beta1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.Equal(t, gateway.Spec.Servers[0], mutatedGateway.Spec.Servers[0])
 
 	assert.NotNil(t, mutatedGateway.Annotations)
@@ -655,7 +855,7 @@ func TestMutateV1WithNoMetadata(t *testing.T) {
 		},
 	}
 
-	mutatedGateway := mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns)
+	mutatedGateway := mutateV1(context.TODO(), gateway.DeepCopy(), eDNS, &ns, naming.GranularityServer, "default")
 	assert.Equal(t, gateway.Spec.Servers[0], mutatedGateway.Spec.Servers[0])
 
 	assert.NotNil(t, mutatedGateway.Annotations)
@@ -663,3 +863,323 @@ func TestMutateV1WithNoMetadata(t *testing.T) {
 	assert.False(t, found)
 	assert.Equal(t, "vanity-target", mutatedGateway.Annotations[v1beta1labels.ExternalDNSTargetAnnotationKey])
 }
+
+func TestGatewayMutationHookGatewayAPI(t *testing.T) {
+	t.Parallel()
+
+	gmh := NewGatewayMutationHook(istiofake.NewSimpleClientset(), &fakeNSLister{})
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(gatewayapiv1beta1.AddToScheme(scheme))
+
+	decoder := admission.NewDecoder(scheme)
+	gmh.InjectDecoder(decoder)
+
+	gateway := &gatewayapiv1beta1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-gateway",
+			Namespace: "devops",
+			Labels: map[string]string{
+				v1beta1labels.InjectSimpleCredentialNameLabel: "true",
+			},
+		},
+		Spec: gatewayapiv1beta1.GatewaySpec{
+			GatewayClassName: "istio",
+			Listeners: []gatewayapiv1beta1.Listener{
+				{
+					Name:     "https",
+					Port:     443,
+					Protocol: gatewayapiv1beta1.HTTPSProtocolType,
+					TLS:      &gatewayapiv1beta1.GatewayTLSConfig{},
+				},
+			},
+		},
+	}
+
+	gatewayBytes, err := json.Marshal(gateway)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		description string
+		request     admissionv1.AdmissionRequest
+		wantAllowed bool
+		wantPatches bool
+	}{
+		{
+			description: "Empty AdmissionRequest should be rejected",
+			request: admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{
+					Group:   gatewayapiv1beta1.GroupName,
+					Version: "v1beta1",
+					Kind:    "Gateway",
+				},
+			},
+			wantAllowed: false,
+		},
+		{
+			description: "Unsupported Gateway API version should be rejected",
+			request: admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{
+					Group:   gatewayapiv1beta1.GroupName,
+					Version: "v1alpha2",
+					Kind:    "Gateway",
+				},
+				Object: runtime.RawExtension{
+					Raw: gatewayBytes,
+				},
+			},
+			wantAllowed: false,
+		},
+		{
+			description: "Successful AdmissionRequest with v1beta1 Gateway API Gateway",
+			request: admissionv1.AdmissionRequest{
+				Kind: metav1.GroupVersionKind{
+					Group:   gatewayapiv1beta1.GroupName,
+					Version: "v1beta1",
+					Kind:    "Gateway",
+				},
+				Object: runtime.RawExtension{
+					Raw: gatewayBytes,
+				},
+			},
+			wantAllowed: true,
+			wantPatches: true,
+		},
+	}
+
+	for _, test := range tests {
+		response := gmh.Handle(context.TODO(), admission.Request{AdmissionRequest: test.request})
+		assert.Equal(t, test.wantAllowed, response.Allowed, test.description)
+		assert.Equal(t, test.wantPatches, len(response.Patches) > 0, test.description)
+	}
+}
+
+func TestMutateGatewayAPIV1Beta1(t *testing.T) {
+	t.Parallel()
+
+	terminate := gatewayapiv1beta1.TLSModeTerminate
+	passthrough := gatewayapiv1beta1.TLSModePassthrough
+
+	gateway := gatewayapiv1beta1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-gateway",
+			Namespace: "devops",
+			Labels:    map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		},
+		Spec: gatewayapiv1beta1.GatewaySpec{
+			Listeners: []gatewayapiv1beta1.Listener{
+				{
+					Name:     "http",
+					Port:     80,
+					Protocol: gatewayapiv1beta1.HTTPProtocolType,
+				},
+				{
+					Name:     "tls",
+					Port:     443,
+					Protocol: gatewayapiv1beta1.TLSProtocolType,
+					TLS: &gatewayapiv1beta1.GatewayTLSConfig{
+						Mode: &passthrough,
+					},
+				},
+				{
+					Name:     "https",
+					Port:     443,
+					Protocol: gatewayapiv1beta1.HTTPSProtocolType,
+					TLS: &gatewayapiv1beta1.GatewayTLSConfig{
+						Mode: &terminate,
+						CertificateRefs: []gatewayapiv1beta1.SecretObjectReference{
+							{Name: "should-be-replaced"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	mutatedGateway := mutateGatewayAPIV1Beta1(context.TODO(), gateway.DeepCopy(), naming.GranularityServer)
+
+	assert.Equal(t, gateway.Spec.Listeners[0], mutatedGateway.Spec.Listeners[0])
+	assert.Equal(t, gateway.Spec.Listeners[1], mutatedGateway.Spec.Listeners[1])
+	assert.Len(t, mutatedGateway.Spec.Listeners[2].TLS.CertificateRefs, 1)
+
+	ref := mutatedGateway.Spec.Listeners[2].TLS.CertificateRefs[0]
+	assert.Equal(t, gatewayapiv1beta1.ObjectName("devops-example-gateway-https"), ref.Name)
+	assert.Equal(t, gatewayapiv1beta1.Kind("Secret"), *ref.Kind)
+	assert.Equal(t, gatewayapiv1beta1.Group(""), *ref.Group)
+	assert.Nil(t, ref.Namespace)
+
+	// Ensure we don't mutate gateways without our tls label
+	gateway.Labels = map[string]string{}
+	mutatedGateway = mutateGatewayAPIV1Beta1(context.TODO(), gateway.DeepCopy(), naming.GranularityServer)
+	assert.Equal(t, gateway.Spec, mutatedGateway.Spec)
+}
+
+func TestServerCredentialName(t *testing.T) {
+	t.Parallel()
+
+	gateway := &networkingv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "example-gateway", Namespace: "devops"}}
+	annotated := gateway.DeepCopy()
+	annotated.Annotations = map[string]string{v1beta1labels.GranularityAnnotation: "gateway"}
+	issuer := v1certmanagermeta.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "cert-manager.io"}
+
+	tests := []struct {
+		description string
+		gateway     *networkingv1.Gateway
+		granularity naming.Granularity
+		hosts       []string
+		shareable   bool
+		want        string
+	}{
+		{description: "server", gateway: gateway, granularity: naming.GranularityServer, hosts: []string{"a.example.com"}, want: "devops-example-gateway-https"},
+		{description: "gateway", gateway: gateway, granularity: naming.GranularityGateway, hosts: []string{"a.example.com"}, want: "devops-example-gateway"},
+		{description: "host", gateway: gateway, granularity: naming.GranularityHost, hosts: []string{"devops/A.example.com"}, want: "devops-example-gateway-a.example.com"},
+		{description: "wildcard host", gateway: gateway, granularity: naming.GranularityHost, hosts: []string{"*/*.example.com"}, want: "devops-example-gateway-wildcard.example.com"},
+		{description: "host with several hosts", gateway: gateway, granularity: naming.GranularityHost, hosts: []string{"a.example.com", "b.example.com"}, want: "devops-example-gateway-https"},
+		{description: "host that cannot be issued", gateway: gateway, granularity: naming.GranularityHost, hosts: []string{"*"}, want: "devops-example-gateway-https"},
+		{description: "annotation overrides the default", gateway: annotated, granularity: naming.GranularityHost, hosts: []string{"a.example.com"}, want: "devops-example-gateway"},
+		{description: "shared", gateway: gateway, granularity: naming.GranularityShared, hosts: []string{"a.example.com"}, shareable: true, want: "shared-8bbd3816895606a5cf22"},
+		{description: "shared server that is not shareable", gateway: gateway, granularity: naming.GranularityShared, hosts: []string{"a.example.com"}, want: "devops-example-gateway-https"},
+		{description: "shared without issuable hosts", gateway: gateway, granularity: naming.GranularityShared, hosts: []string{"*"}, shareable: true, want: "devops-example-gateway-https"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, serverCredentialName(context.TODO(), test.gateway, test.granularity, "https", test.hosts, issuer, test.shareable), test.description)
+	}
+}
+
+func TestMutateV1SharedGranularity(t *testing.T) {
+	t.Parallel()
+
+	newGateway := func(name, namespace string, hosts ...string) *networkingv1.Gateway {
+		return &networkingv1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Labels:      map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+				Annotations: map[string]string{v1beta1labels.GranularityAnnotation: "shared"},
+			},
+			Spec: networkingapiv1.Gateway{
+				Servers: []*networkingapiv1.Server{
+					{
+						Hosts: hosts,
+						Port:  &networkingapiv1.Port{Number: 443, Name: "https"},
+						Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE},
+					},
+					{
+						Hosts: hosts,
+						Port:  &networkingapiv1.Port{Number: 8443, Name: "mtls"},
+						Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_MUTUAL},
+					},
+				},
+			},
+		}
+	}
+
+	blue := mutateV1(context.TODO(), newGateway("blue", "devops", "devops/a.example.com", "b.example.com"), nil, nil, naming.GranularityServer, "default")
+	green := mutateV1(context.TODO(), newGateway("green", "other", "B.example.com", "*/a.example.com"), nil, nil, naming.GranularityServer, "default")
+	canary := mutateV1(context.TODO(), newGateway("canary", "devops", "a.example.com"), nil, nil, naming.GranularityServer, "default")
+
+	assert.Regexp(t, "^shared-[0-9a-f]{20}$", blue.Spec.Servers[0].Tls.CredentialName)
+	assert.Equal(t, blue.Spec.Servers[0].Tls.CredentialName, green.Spec.Servers[0].Tls.CredentialName)
+	assert.NotEqual(t, blue.Spec.Servers[0].Tls.CredentialName, canary.Spec.Servers[0].Tls.CredentialName)
+
+	// mutual TLS servers have a client CA per Gateway and are not shared
+	assert.Equal(t, "devops-blue-mtls", blue.Spec.Servers[1].Tls.CredentialName)
+
+	// Gateways with another issuer get another Certificate
+	other := newGateway("blue", "devops", "a.example.com", "b.example.com")
+	other.Annotations[v1beta1labels.IssuerAnnotation] = "other-issuer"
+	other = mutateV1(context.TODO(), other, nil, nil, naming.GranularityServer, "default")
+	assert.NotEqual(t, blue.Spec.Servers[0].Tls.CredentialName, other.Spec.Servers[0].Tls.CredentialName)
+
+	// the namespace default issuer and allowlist resolve the issuer like the Gateway controller does
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "devops", Annotations: map[string]string{
+		v1beta1labels.IssuerAnnotation:         "other-issuer",
+		v1beta1labels.AllowedIssuersAnnotation: "letsencrypt",
+	}}}
+	defaulted := mutateV1(context.TODO(), newGateway("blue", "devops", "a.example.com", "b.example.com"), nil, ns, naming.GranularityServer, "default")
+	assert.Equal(t, other.Spec.Servers[0].Tls.CredentialName, defaulted.Spec.Servers[0].Tls.CredentialName)
+
+	rejected := newGateway("blue", "devops", "a.example.com", "b.example.com")
+	rejected.Annotations[v1beta1labels.IssuerAnnotation] = "selfsigned"
+	rejected = mutateV1(context.TODO(), rejected, nil, ns, naming.GranularityServer, "default")
+	assert.Equal(t, other.Spec.Servers[0].Tls.CredentialName, rejected.Spec.Servers[0].Tls.CredentialName)
+}
+
+func TestMutateV1Granularity(t *testing.T) {
+	t.Parallel()
+
+	gateway := networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-gateway",
+			Namespace: "devops",
+			Labels:    map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		},
+		Spec: networkingapiv1.Gateway{
+			Servers: []*networkingapiv1.Server{
+				{
+					Hosts: []string{"a.example.com"},
+					Port:  &networkingapiv1.Port{Number: 443, Name: "https"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE},
+				},
+				{
+					Hosts: []string{"b.example.com", "c.example.com"},
+					Port:  &networkingapiv1.Port{Number: 8443, Name: "https-alt"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE},
+				},
+			},
+		},
+	}
+
+	mutated := mutateV1(context.TODO(), gateway.DeepCopy(), nil, nil, naming.GranularityGateway, "default")
+	assert.Equal(t, "devops-example-gateway", mutated.Spec.Servers[0].Tls.CredentialName)
+	assert.Equal(t, "devops-example-gateway", mutated.Spec.Servers[1].Tls.CredentialName)
+
+	mutated = mutateV1(context.TODO(), gateway.DeepCopy(), nil, nil, naming.GranularityHost, "default")
+	assert.Equal(t, "devops-example-gateway-a.example.com", mutated.Spec.Servers[0].Tls.CredentialName)
+	assert.Equal(t, "devops-example-gateway-https-alt", mutated.Spec.Servers[1].Tls.CredentialName)
+
+	warnings := hostGranularityWarnings(mutated, mutated.Spec.Servers, naming.GranularityHost)
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "server https-alt has 2 hosts")
+	assert.Nil(t, hostGranularityWarnings(mutated, mutated.Spec.Servers, naming.GranularityServer))
+}
+
+func TestMutateV1UnmanagedServers(t *testing.T) {
+	t.Parallel()
+
+	gateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "example-gateway",
+			Namespace:   "devops",
+			Labels:      map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+			Annotations: map[string]string{v1beta1labels.UnmanagedServersAnnotation: "https-ev, shop.example.com"},
+		},
+		Spec: networkingapiv1.Gateway{
+			Servers: []*networkingapiv1.Server{
+				{
+					Hosts: []string{"a.example.com"},
+					Port:  &networkingapiv1.Port{Number: 443, Name: "https"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE},
+				},
+				{
+					Hosts: []string{"www.example.com"},
+					Port:  &networkingapiv1.Port{Number: 8443, Name: "https-ev"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE, CredentialName: "ev-cert"},
+				},
+				{
+					Hosts: []string{"devops/Shop.example.com"},
+					Port:  &networkingapiv1.Port{Number: 9443, Name: "https-shop"},
+					Tls:   &networkingapiv1.ServerTLSSettings{Mode: networkingapiv1.ServerTLSSettings_SIMPLE, CredentialName: "shop-cert"},
+				},
+			},
+		},
+	}
+
+	mutated := mutateV1(context.TODO(), gateway.DeepCopy(), nil, nil, naming.GranularityServer, "default")
+	assert.Equal(t, "devops-example-gateway-https", mutated.Spec.Servers[0].Tls.CredentialName)
+	assert.Equal(t, "ev-cert", mutated.Spec.Servers[1].Tls.CredentialName)
+	assert.Equal(t, "shop-cert", mutated.Spec.Servers[2].Tls.CredentialName)
+
+	assert.Equal(t, []string{"devops-example-gateway-https"}, serverCredentialNames(mutated, mutated.Spec.Servers))
+}
@@ -0,0 +1,171 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	networkingapiv1 "istio.io/api/networking/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+// serverCredentialNames returns the credentialNames of the managed servers of a labeled Gateway, unmanaged servers
+// keep the credentialName of the user.
+func serverCredentialNames(gateway client.Object, servers []*networkingapiv1.Server) []string {
+	if val, ok := gateway.GetLabels()[v1beta1labels.InjectSimpleCredentialNameLabel]; !ok || val != "true" {
+		return nil
+	}
+
+	names := sets.New[string]()
+	for _, s := range servers {
+		if s.Tls != nil && naming.IsManagedTLSMode(s.Tls.Mode) && s.Tls.CredentialName != "" && !naming.IsUnmanagedServer(gateway, s.Port.GetName(), s.Hosts) {
+			names.Insert(s.Tls.CredentialName)
+		}
+	}
+
+	return sets.List(names)
+}
+
+// listenerCertificateNames returns the Secrets referenced by the terminating TLS listeners of a labeled Gateway.
+func listenerCertificateNames(gateway *gatewayapiv1beta1.Gateway) []string {
+	if val, ok := gateway.Labels[v1beta1labels.InjectSimpleCredentialNameLabel]; !ok || val != "true" {
+		return nil
+	}
+
+	names := sets.New[string]()
+	for _, l := range gateway.Spec.Listeners {
+		if l.TLS == nil || (l.TLS.Mode != nil && *l.TLS.Mode != gatewayapiv1beta1.TLSModeTerminate) || naming.IsUnmanagedListener(gateway, l) {
+			continue
+		}
+
+		for _, ref := range l.TLS.CertificateRefs {
+			if ref.Namespace == nil {
+				names.Insert(string(ref.Name))
+			}
+		}
+	}
+
+	return sets.List(names)
+}
+
+// certificateNameIndex indexes the cached managed Certificates by name, across namespaces.
+const certificateNameIndex = "name"
+
+// indexByName returns the name of a cached Certificate.
+func indexByName(obj interface{}) ([]string, error) {
+	cert, ok := obj.(*v1certmanager.Certificate)
+	if !ok {
+		return nil, nil
+	}
+
+	return []string{cert.Name}, nil
+}
+
+// credentialNamesUnchanged reports whether the request updates a Gateway that already had the credentialNames, e.g.
+// a status or annotation patch, whose conflicts were checked when they were admitted.  oldNames returns the
+// credentialNames of old once the old object is decoded into it.
+func (g *GatewayMutationHook) credentialNamesUnchanged(req admission.Request, names []string, old runtime.Object, oldNames func() []string) bool {
+	if req.Operation != admissionv1.Update || len(req.OldObject.Raw) == 0 {
+		return false
+	}
+
+	if err := g.decoder.DecodeRaw(req.OldObject, old); err != nil {
+		return false
+	}
+
+	return slices.Equal(names, oldNames())
+}
+
+// credentialNameConflicts returns a message for each generated credentialName already used by a managed Certificate
+// of another Gateway that still exists, e.g. Gateways whose "<namespace>-<name>" prefixes are equal.  Both Gateways
+// would otherwise update the same Certificate and garbage collection could delete it from under one of them.
+// Shared Certificates are meant to be used by several Gateways and Certificates of Gateways that no longer exist
+// are taken over, so neither conflicts.  Conflicts are not checked without a cert-manager client or before the
+// Certificate cache has synced, and lookup errors are logged and ignored, so the webhook does not block Gateways
+// while the API server is degraded.
+func (g *GatewayMutationHook) credentialNameConflicts(ctx context.Context, gateway client.Object, names []string) []string {
+	if g.certificates == nil || len(names) == 0 {
+		return nil
+	}
+
+	log := log.FromContext(ctx)
+	if !g.certificates.HasSynced() {
+		log.Info("Certificate cache has not synced, skipping credentialName conflict check", "gateway", gateway.GetName(), "namespace", gateway.GetNamespace())
+		return nil
+	}
+
+	conflicts := []string{}
+	for _, name := range names {
+		objs, err := g.certificates.GetIndexer().ByIndex(certificateNameIndex, name)
+		if err != nil {
+			log.Error(err, "failed to read Certificates, skipping credentialName conflict check", "credentialName", name)
+			continue
+		}
+
+		for _, obj := range objs {
+			cert, ok := obj.(*v1certmanager.Certificate)
+			if !ok {
+				continue
+			}
+
+			ownerName, ownerNamespace := v1beta1labels.ManagedGateway(cert.Labels, cert.Annotations)
+			if cert.Name != name || (ownerName == gateway.GetName() && ownerNamespace == gateway.GetNamespace()) {
+				continue
+			}
+
+			if _, ok := cert.Annotations[v1beta1labels.ReferencedByAnnotation]; ok {
+				continue
+			}
+
+			exists, err := g.ownerExists(ctx, cert, ownerNamespace, ownerName)
+			if err != nil {
+				log.Error(err, "failed to get Gateway, skipping credentialName conflict check", "credentialName", name, "gateway-namespace", ownerNamespace, "gateway", ownerName)
+				continue
+			}
+
+			if exists {
+				conflicts = append(conflicts, fmt.Sprintf("credentialName %s is already used by Certificate %s/%s of Gateway %s/%s", name, cert.Namespace, cert.Name, ownerNamespace, ownerName))
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// ownerExists reports whether the Gateway a Certificate is managed for exists.  Gateway API Gateways are assumed
+// to exist without a Gateway API client.
+func (g *GatewayMutationHook) ownerExists(ctx context.Context, cert *v1certmanager.Certificate, namespace, name string) (bool, error) {
+	if name == "" || namespace == "" {
+		return false, nil
+	}
+
+	var err error
+	if cert.Annotations[v1beta1labels.ManagedGroupAnnotation] == gatewayapiv1beta1.GroupName {
+		if g.gatewayAPIClient == nil {
+			return true, nil
+		}
+		_, err = g.gatewayAPIClient.GatewayV1beta1().Gateways(namespace).Get(ctx, name, metav1.GetOptions{})
+	} else {
+		_, err = g.istioClient.NetworkingV1().Gateways(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
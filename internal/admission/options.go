@@ -1,7 +1,16 @@
 package admission
 
 import (
+	"time"
+
+	certmanagerversionedclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	certmanagerinformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	k8scache "k8s.io/client-go/tools/cache"
+	gatewayapiclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 )
 
 type OptionsFunc func(*GatewayMutationHook)
@@ -17,3 +26,47 @@ func WithNSLister(nsl corev1listers.NamespaceLister) OptionsFunc {
 		gmh.nsLister = nsl
 	}
 }
+
+// WithMaxSANsPerCertificate warns when a managed server has more hosts than fit in a single Certificate.
+func WithMaxSANsPerCertificate(max int) OptionsFunc {
+	return func(gmh *GatewayMutationHook) {
+		gmh.maxSANs = max
+	}
+}
+
+// WithCertificateGranularity sets the default granularity credentialNames are named with, Gateways may
+// override it with the granularity annotation.
+func WithCertificateGranularity(g naming.Granularity) OptionsFunc {
+	return func(gmh *GatewayMutationHook) {
+		gmh.granularity = g
+	}
+}
+
+// WithDefaultClusterIssuer sets the ClusterIssuer shared credentialNames are named after when neither the
+// Gateway nor its namespace annotate an issuer, it must match the issuer of the Gateway controller.
+func WithDefaultClusterIssuer(issuer string) OptionsFunc {
+	return func(gmh *GatewayMutationHook) {
+		gmh.clusterIssuer = issuer
+	}
+}
+
+// WithCertificateClient denies Gateways whose generated credentialNames are used by a Certificate of another
+// Gateway.  Managed Certificates are read from an informer cache indexed by name, started by SetupWithManager, so
+// admissions do not list Certificates from the API server.
+func WithCertificateClient(client certmanagerversionedclient.Interface) OptionsFunc {
+	return func(gmh *GatewayMutationHook) {
+		gmh.certInformerFactory = certmanagerinformers.NewSharedInformerFactoryWithOptions(client, time.Second*30, certmanagerinformers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+			listOptions.LabelSelector = v1beta1labels.ManagedLabelSelector()
+		}))
+		gmh.certificates = gmh.certInformerFactory.Certmanager().V1().Certificates().Informer()
+		// the informer has not started, AddIndexers only fails once it runs
+		_ = gmh.certificates.AddIndexers(k8scache.Indexers{certificateNameIndex: indexByName})
+	}
+}
+
+// WithGatewayAPIClient checks whether the Gateway API Gateway owning a conflicting Certificate still exists.
+func WithGatewayAPIClient(client gatewayapiclient.Interface) OptionsFunc {
+	return func(gmh *GatewayMutationHook) {
+		gmh.gatewayAPIClient = client
+	}
+}
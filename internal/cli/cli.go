@@ -9,6 +9,7 @@ import (
 	// import oidc auth
 	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/cache"
 	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/challengesolver"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	k8scache "k8s.io/client-go/tools/cache"
@@ -16,12 +17,16 @@ import (
 	"github.com/kanopy-platform/gateway-certificate-controller/internal/admission"
 	v1beta1gc "github.com/kanopy-platform/gateway-certificate-controller/internal/controllers/v1beta1/garbagecollection"
 	v1beta1controllers "github.com/kanopy-platform/gateway-certificate-controller/internal/controllers/v1beta1/gateway"
+	v1beta1status "github.com/kanopy-platform/gateway-certificate-controller/internal/controllers/v1beta1/status"
+	"github.com/kanopy-platform/gateway-certificate-controller/internal/discovery"
 	logzap "github.com/kanopy-platform/gateway-certificate-controller/internal/log/zap"
+	"github.com/kanopy-platform/gateway-certificate-controller/internal/workload"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	istioversionedclient "istio.io/client-go/pkg/clientset/versioned"
 
+	acmev1 "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
 	certmanagerv1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	certmanagerversionedclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
 	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
@@ -29,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	k8sdiscovery "k8s.io/client-go/discovery"
 	k8sinformers "k8s.io/client-go/informers"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
@@ -38,14 +44,23 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapiversionedclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
 )
 
 var scheme = runtime.NewScheme()
 
+const (
+	certificateNamespaceModeWorkload = "workload"
+	certificateNamespaceModeFixed    = "fixed"
+)
+
 func init() {
 	utilruntime.Must(networkingv1beta1.SchemeBuilder.AddToScheme(scheme))
 	utilruntime.Must(networkingv1.SchemeBuilder.AddToScheme(scheme))
 	utilruntime.Must(certmanagerv1.SchemeBuilder.AddToScheme(scheme))
+	utilruntime.Must(acmev1.SchemeBuilder.AddToScheme(scheme))
+	utilruntime.Must(gatewayapiv1beta1.AddToScheme(scheme))
 }
 
 // RootCommand is the origin of all command life
@@ -74,9 +89,19 @@ func NewRootCommand() *cobra.Command {
 	cmd.PersistentFlags().String("external-dns-target", "", "Set or delete value for the external-dns target annotation, implies --external-dns, default: delete")
 	cmd.PersistentFlags().String("external-dns-selector", "", "Annotation key=value selector string to use for excluding namespace from mutation, implies --external-dns, default: ingress-whitelist=*")
 	cmd.PersistentFlags().Bool("dry-run", false, "Controller dry-run changes only")
-	cmd.PersistentFlags().String("certificate-namespace", "cert-manager", "Namespace that stores Certificates")
+	cmd.PersistentFlags().String("certificate-namespace", "cert-manager", "Namespace that stores Certificates when --certificate-namespace-mode=fixed")
+	cmd.PersistentFlags().String("certificate-granularity", string(naming.GranularityServer), "How TLS servers are grouped into Certificates, one of: server, gateway, host, shared. Gateways may override it with an annotation")
+	cmd.PersistentFlags().String("certificate-adoption-policy", string(v1beta1controllers.AdoptionPolicyRefuse), "What to do with existing Certificates named like a managed Certificate that lack the managed label, one of: refuse, adopt-with-label, adopt-if-hosts-match")
+	cmd.PersistentFlags().String("certificate-namespace-mode", certificateNamespaceModeFixed, "Where Istio Gateway Certificates are created, one of: fixed (--certificate-namespace), workload (the namespace of the gateway workload, caches every Pod and Service in the cluster)")
 	cmd.PersistentFlags().String("default-issuer", "selfsigned", "The default ClusterIssuer")
+	cmd.PersistentFlags().Bool("gateway-api", false, "Enable Kubernetes Gateway API (gateway.networking.k8s.io) Gateway support")
+	cmd.PersistentFlags().Bool("gateway-status", true, "Report managed Certificate status onto Gateways with an annotation")
 	cmd.PersistentFlags().String("http-solver-label", "use-istio-http01-solver", "The cert-manager http01 solver selector label to apply to Certificates")
+	cmd.PersistentFlags().Bool("gc-delete-secrets", false, "Delete the TLS Secret of garbage collected Certificates and sweep the Secrets of managed Certificates that no longer exist")
+	cmd.PersistentFlags().Duration("gc-secret-sweep-interval", time.Hour, "How often the Secrets of managed Certificates are checked for a Certificate that no longer exists with --gc-delete-secrets, 0 disables the sweep")
+	cmd.PersistentFlags().Duration("gc-grace-period", 10*time.Minute, "How long an unused Certificate is kept before it is garbage collected, 0 deletes it immediately")
+	cmd.PersistentFlags().Duration("gc-sweep-interval", 10*time.Minute, "How often every managed Certificate is checked against the Gateways and orphans are reported and collected, 0 disables the sweep")
+	cmd.PersistentFlags().Int("max-sans-per-certificate", 100, "Split the hosts of a server across several Certificates above this many names, 0 disables splitting")
 
 	k8sFlags.AddFlags(cmd.PersistentFlags())
 	// no need to check err, this only checks if variadic args != 0
@@ -113,6 +138,21 @@ func (c *RootCommand) runE(cmd *cobra.Command, args []string) error {
 		klog.Log.Info("running in dry-run mode")
 	}
 
+	certificateNamespaceMode := viper.GetString("certificate-namespace-mode")
+	if certificateNamespaceMode != certificateNamespaceModeWorkload && certificateNamespaceMode != certificateNamespaceModeFixed {
+		return fmt.Errorf("invalid --certificate-namespace-mode %q, must be one of: %s, %s", certificateNamespaceMode, certificateNamespaceModeWorkload, certificateNamespaceModeFixed)
+	}
+
+	granularity, err := naming.ParseGranularity(viper.GetString("certificate-granularity"))
+	if err != nil {
+		return err
+	}
+
+	adoptionPolicy, err := v1beta1controllers.ParseAdoptionPolicy(viper.GetString("certificate-adoption-policy"))
+	if err != nil {
+		return err
+	}
+
 	cfg, err := c.k8sFlags.ToRESTConfig()
 	if err != nil {
 		return err
@@ -128,6 +168,15 @@ func (c *RootCommand) runE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := checkServedAPIs(clientset.Discovery(), viper.GetBool("gateway-api")); err != nil {
+		return err
+	}
+
 	ctx := signals.SetupSignalHandler()
 
 	mgr, err := manager.New(cfg, manager.Options{
@@ -158,22 +207,86 @@ func (c *RootCommand) runE(cmd *cobra.Command, args []string) error {
 
 	glc := cache.New()
 
-	if err := v1beta1controllers.NewGatewayController(ic, cmc,
+	k8sInformerFactory := k8sinformers.NewSharedInformerFactoryWithOptions(clientset, time.Second*30)
+	coreV1Informer := k8sInformerFactory.Core().V1()
+	nsInformer := coreV1Informer.Namespaces()
+
+	gatewayOpts := []v1beta1controllers.OptionsFunc{
 		v1beta1controllers.WithDryRun(viper.GetBool("dry-run")),
 		v1beta1controllers.WithDefaultClusterIssuer(viper.GetString("default-issuer")),
 		v1beta1controllers.WithCertificateNamespace(viper.GetString("certificate-namespace")),
 		v1beta1controllers.WithGatewayLookupCache(glc),
-		v1beta1controllers.WithHTTPSolverLabel(viper.GetString("http-solver-label"))).
+		v1beta1controllers.WithHTTPSolverLabel(viper.GetString("http-solver-label")),
+		v1beta1controllers.WithEventRecorder(mgr.GetEventRecorderFor("istio-gateway-controller")),
+		v1beta1controllers.WithKubernetesClient(clientset),
+		v1beta1controllers.WithMaxSANsPerCertificate(viper.GetInt("max-sans-per-certificate")),
+		v1beta1controllers.WithAdoptionPolicy(adoptionPolicy),
+		v1beta1controllers.WithNamespaceInformer(nsInformer),
+	}
+
+	gcOpts := []v1beta1gc.OptionsFunc{
+		v1beta1gc.WithDryRun(dryRun),
+		v1beta1gc.WithGracePeriod(viper.GetDuration("gc-grace-period")),
+		v1beta1gc.WithSweepInterval(viper.GetDuration("gc-sweep-interval")),
+		v1beta1gc.WithEventRecorder(mgr.GetEventRecorderFor("istio-garbage-collection-controller")),
+	}
+
+	if viper.GetBool("gc-delete-secrets") {
+		gcOpts = append(gcOpts,
+			v1beta1gc.WithSecretDeletion(clientset),
+			v1beta1gc.WithSecretSweepInterval(viper.GetDuration("gc-secret-sweep-interval")))
+	}
+
+	if certificateNamespaceMode == certificateNamespaceModeWorkload {
+		// the listers register their informers, which are started with the factory below.  The Pod informer caches
+		// every Pod in the cluster, so it is only registered in workload mode
+		resolver := workload.NewNamespaceResolver(coreV1Informer.Pods().Lister(), coreV1Informer.Services().Lister())
+		gatewayOpts = append(gatewayOpts, v1beta1controllers.WithNamespaceResolver(resolver))
+		gcOpts = append(gcOpts, v1beta1gc.WithNamespaceResolver(resolver))
+	}
+
+	if err := v1beta1controllers.NewGatewayController(ic, cmc, gatewayOpts...).
 		SetupWithManager(ctx, mgr); err != nil {
 		return err
 	}
+	statusOpts := []v1beta1status.OptionsFunc{v1beta1status.WithDryRun(dryRun)}
+	admissionOpts := []admission.OptionsFunc{admission.WithCertificateClient(cmc)}
+
+	if viper.GetBool("gateway-api") {
+		gwc, err := gatewayapiversionedclient.NewForConfig(cfg)
+		if err != nil {
+			return err
+		}
 
-	if err := v1beta1gc.NewGarbageCollectionController(ic, cmc,
-		v1beta1gc.WithDryRun(dryRun)).
+		if err := v1beta1controllers.NewGatewayAPIController(gwc, cmc,
+			v1beta1controllers.WithDryRun(dryRun),
+			v1beta1controllers.WithDefaultClusterIssuer(viper.GetString("default-issuer")),
+			v1beta1controllers.WithHTTPSolverLabel(viper.GetString("http-solver-label")),
+			v1beta1controllers.WithEventRecorder(mgr.GetEventRecorderFor("gateway-api-controller")),
+			v1beta1controllers.WithKubernetesClient(clientset),
+			v1beta1controllers.WithAdoptionPolicy(adoptionPolicy),
+			v1beta1controllers.WithNamespaceInformer(nsInformer)).
+			SetupWithManager(ctx, mgr); err != nil {
+			return err
+		}
+
+		gcOpts = append(gcOpts, v1beta1gc.WithGatewayAPIClient(gwc))
+		statusOpts = append(statusOpts, v1beta1status.WithGatewayAPIClient(gwc))
+		admissionOpts = append(admissionOpts, admission.WithGatewayAPIClient(gwc))
+	}
+
+	if err := v1beta1gc.NewGarbageCollectionController(ic, cmc, gcOpts...).
 		SetupWithManager(ctx, mgr); err != nil {
 		return err
 	}
 
+	if viper.GetBool("gateway-status") {
+		if err := v1beta1status.NewStatusController(ic, cmc, statusOpts...).
+			SetupWithManager(ctx, mgr); err != nil {
+			return err
+		}
+	}
+
 	edc := admission.NewExternalDNSConfig()
 	externalDNSTarget := viper.GetString("external-dns-target")
 	externalDNSSelector := viper.GetString("external-dns-selector")
@@ -198,15 +311,6 @@ func (c *RootCommand) runE(cmd *cobra.Command, args []string) error {
 
 	edc.SetEnabled(externalDNSEnabled)
 
-	clientset, err := kubernetes.NewForConfig(cfg)
-	if err != nil {
-		return err
-	}
-
-	k8sInformerFactory := k8sinformers.NewSharedInformerFactoryWithOptions(clientset, time.Second*30)
-
-	nsInformer := k8sInformerFactory.Core().V1().Namespaces()
-
 	// need at least one listener func to populate the in memory cache
 	_, err = nsInformer.Informer().AddEventHandler(k8scache.ResourceEventHandlerFuncs{
 		AddFunc: func(new interface{}) {},
@@ -216,8 +320,6 @@ func (c *RootCommand) runE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	coreV1Informer := k8sInformerFactory.Core().V1()
-
 	_, err = coreV1Informer.Services().Informer().AddEventHandler(k8scache.ResourceEventHandlerFuncs{
 		AddFunc: func(new interface{}) {},
 	})
@@ -233,7 +335,9 @@ func (c *RootCommand) runE(cmd *cobra.Command, args []string) error {
 	serviceLister := coreV1Informer.Services().Lister()
 
 	if viper.GetBool("challenge-solver") {
-		cs := challengesolver.NewChallengeSolver(serviceLister, ic.NetworkingV1beta1(), cmc, glc, challengesolver.WithDryRun(dryRun))
+		cs := challengesolver.NewChallengeSolver(serviceLister, ic.NetworkingV1(), cmc, glc,
+			challengesolver.WithDryRun(dryRun),
+			challengesolver.WithEventRecorder(mgr.GetEventRecorderFor("challengesolver")))
 
 		err = cs.SetupWithManager(ctx, mgr)
 		if err != nil {
@@ -241,14 +345,52 @@ func (c *RootCommand) runE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	admission.NewGatewayMutationHook(
-		ic,
-		nsl,
-		admission.WithExternalDNSConfig(edc)).SetupWithManager(mgr)
+	admissionOpts = append(admissionOpts,
+		admission.WithExternalDNSConfig(edc),
+		admission.WithMaxSANsPerCertificate(viper.GetInt("max-sans-per-certificate")),
+		admission.WithCertificateGranularity(granularity),
+		admission.WithDefaultClusterIssuer(viper.GetString("default-issuer")))
+
+	if err := admission.NewGatewayMutationHook(ic, nsl, admissionOpts...).SetupWithManager(mgr); err != nil {
+		return err
+	}
 
 	return mgr.Start(ctx)
 }
 
+// checkServedAPIs fails fast when the cluster does not serve the Gateway API versions the controllers watch.
+func checkServedAPIs(dc k8sdiscovery.DiscoveryInterface, gatewayAPI bool) error {
+	versions, err := discovery.ServedVersions(dc, networkingv1.SchemeGroupVersion.Group)
+	if err != nil {
+		return err
+	}
+	klog.Log.Info("discovered istio networking API versions", "group", networkingv1.SchemeGroupVersion.Group, "versions", versions)
+
+	ok, err := discovery.ServesResource(dc, networkingv1.SchemeGroupVersion, "gateways")
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("gateways.%s/%s is not served by the cluster (served versions: %v), Istio 1.22 or later is required, see docs/installation.md",
+			networkingv1.SchemeGroupVersion.Group, networkingv1.SchemeGroupVersion.Version, versions)
+	}
+
+	if gatewayAPI {
+		ok, err := discovery.ServesResource(dc, gatewayapiv1beta1.SchemeGroupVersion, "gateways")
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("gateways.%s/%s is not served by the cluster, install the Gateway API CRDs or disable --gateway-api",
+				gatewayapiv1beta1.SchemeGroupVersion.Group, gatewayapiv1beta1.SchemeGroupVersion.Version)
+		}
+	}
+
+	return nil
+}
+
 func configureHealthChecks(mgr manager.Manager) error {
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		return err
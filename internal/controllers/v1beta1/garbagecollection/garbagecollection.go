@@ -2,19 +2,30 @@ package garbagecollection
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	certmanagerversionedclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
 	certmanagerinformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
 	"github.com/kanopy-platform/gateway-certificate-controller/internal/prometheus"
 	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
-	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
 	istioversionedclient "istio.io/client-go/pkg/clientset/versioned"
+	istioinformers "istio.io/client-go/pkg/informers/externalversions"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -22,22 +33,48 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapiclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+	gatewayapiinformers "sigs.k8s.io/gateway-api/pkg/client/informers/externalversions"
+
+	apinetworkingv1 "istio.io/api/networking/v1"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 )
 
 type GarbageCollectionController struct {
-	name              string
-	certmanagerClient certmanagerversionedclient.Interface
-	istioClient       istioversionedclient.Interface
-	dryRun            bool
-	managedCerts      map[string]bool
+	name                string
+	certmanagerClient   certmanagerversionedclient.Interface
+	istioClient         istioversionedclient.Interface
+	gatewayAPIClient    gatewayapiclient.Interface
+	coreClient          kubernetes.Interface
+	dryRun              bool
+	managedCerts        map[string]bool
+	retainedCerts       map[string]bool
+	recorder            record.EventRecorder
+	namespaceResolver   NamespaceResolver
+	gracePeriod         time.Duration
+	sweepInterval       time.Duration
+	secretSweepInterval time.Duration
+	sweepEvents         chan event.GenericEvent
+	now                 func() time.Time // time.Now when nil, overridden in tests
 }
 
+const (
+	reasonCertificateDeleted      = "CertificateDeleted"
+	reasonCertificateDeleteFailed = "CertificateDeleteFailed"
+	reasonGatewayGetFailed        = "GatewayGetFailed"
+	reasonDeletionPending         = "CertificateDeletionPending"
+	reasonDeletionCancelled       = "CertificateDeletionCancelled"
+)
+
 func NewGarbageCollectionController(istioClient istioversionedclient.Interface, certClient certmanagerversionedclient.Interface, opts ...OptionsFunc) *GarbageCollectionController {
 	gc := &GarbageCollectionController{
 		name:              "istio-garbage-collection-controller",
 		certmanagerClient: certClient,
 		istioClient:       istioClient,
 		managedCerts:      make(map[string]bool),
+		retainedCerts:     make(map[string]bool),
 	}
 
 	for _, opt := range opts {
@@ -60,8 +97,9 @@ func (c *GarbageCollectionController) SetupWithManager(ctx context.Context, mgr
 		listOptions.LabelSelector = v1beta1labels.ManagedLabelSelector()
 	}))
 
+	certificates := certmanagerInformerFactory.Certmanager().V1().Certificates()
 	if err := ctrl.Watch(&source.Informer{
-		Informer: certmanagerInformerFactory.Certmanager().V1().Certificates().Informer(),
+		Informer: certificates.Informer(),
 		Handler: handler.Funcs{
 			// only handle Update so that Deleting a certificate does not trigger another Reconcile
 			// Create will also trigger an Update
@@ -71,7 +109,44 @@ func (c *GarbageCollectionController) SetupWithManager(ctx context.Context, mgr
 		return err
 	}
 
+	// Gateway deletes and changes enqueue their Certificates directly rather than waiting for the Certificate resync
+	istioInformerFactory := istioinformers.NewSharedInformerFactoryWithOptions(c.istioClient, time.Second*30)
+	if err := ctrl.Watch(&source.Informer{
+		Informer: istioInformerFactory.Networking().V1().Gateways().Informer(),
+		Handler:  certificatesForGateway(certificates.Lister()),
+	}); err != nil {
+		return err
+	}
+
+	if c.gatewayAPIClient != nil {
+		gatewayInformerFactory := gatewayapiinformers.NewSharedInformerFactoryWithOptions(c.gatewayAPIClient, time.Second*30)
+		if err := ctrl.Watch(&source.Informer{
+			Informer: gatewayInformerFactory.Gateway().V1beta1().Gateways().Informer(),
+			Handler:  certificatesForGateway(certificates.Lister()),
+		}); err != nil {
+			return err
+		}
+
+		gatewayInformerFactory.Start(ctx.Done())
+	}
+
 	certmanagerInformerFactory.Start(ctx.Done())
+	istioInformerFactory.Start(ctx.Done())
+
+	if c.sweepInterval > 0 {
+		c.sweepEvents = make(chan event.GenericEvent)
+		if err := ctrl.Watch(source.Channel(c.sweepEvents, &handler.EnqueueRequestForObject{})); err != nil {
+			return err
+		}
+
+		if err := mgr.Add(manager.RunnableFunc(c.runCertificateSweep)); err != nil {
+			return err
+		}
+	}
+
+	if c.coreClient != nil && c.secretSweepInterval > 0 {
+		return mgr.Add(manager.RunnableFunc(c.runSecretSweep))
+	}
 
 	return nil
 }
@@ -85,13 +160,22 @@ func (c *GarbageCollectionController) Reconcile(ctx context.Context, request rec
 	certIface := c.certmanagerClient.CertmanagerV1().Certificates(request.Namespace)
 	cert, err := certIface.Get(ctx, request.Name, metav1.GetOptions{})
 	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// already deleted, e.g. enqueued by the sweep or a Gateway delete after it was collected
+			log.V(1).Info("Certificate not found, skipping", "certificate", request.String())
+			delete(c.managedCerts, request.String())
+			delete(c.retainedCerts, request.String())
+			prometheus.UpdateManagedCertificatesCount(len(c.managedCerts))
+			prometheus.UpdateRetainedCertificatesCount(len(c.retainedCerts))
+			return reconcile.Result{}, nil
+		}
 		log.Error(err, "failed to Get Certificate")
 		return reconcile.Result{
 			Requeue: true,
 		}, err
 	}
 
-	gatewayName, gatewayNamespace := v1beta1labels.ParseManagedLabel(cert.Labels[v1beta1labels.ManagedLabel])
+	gatewayName, gatewayNamespace := v1beta1labels.ManagedGateway(cert.Labels, cert.Annotations)
 
 	deleteCert := false
 	deleteOptions := metav1.DeleteOptions{}
@@ -99,24 +183,92 @@ func (c *GarbageCollectionController) Reconcile(ctx context.Context, request rec
 		deleteOptions.DryRun = []string{metav1.DryRunAll}
 	}
 
-	gateway, err := c.istioClient.NetworkingV1beta1().Gateways(gatewayNamespace).Get(ctx, gatewayName, metav1.GetOptions{})
-	if k8serrors.IsNotFound(err) {
+	var gateway client.Object
+	var certificateInUse bool
+	if cert.Annotations[v1beta1labels.ManagedGroupAnnotation] == gatewayapiv1beta1.GroupName {
+		if c.gatewayAPIClient == nil {
+			log.V(1).Info("Gateway API support is disabled, skipping Certificate", "certificate", request.String())
+			return reconcile.Result{}, nil
+		}
+
+		gateway, certificateInUse, err = c.inspectGatewayAPIGateway(ctx, cert, gatewayNamespace, gatewayName)
+	} else if _, ok := cert.Annotations[v1beta1labels.ReferencedByAnnotation]; ok {
+		gateway, certificateInUse, err = c.inspectSharedCertificate(ctx, cert)
+	} else {
+		gateway, certificateInUse, err = c.inspectGateway(ctx, cert, gatewayNamespace, gatewayName)
+	}
+
+	if err != nil {
+		log.Error(err, "failed to Get Gateway", "gateway-namespace", gatewayNamespace, "gateway", gatewayName)
+		c.recordEvent(cert, corev1.EventTypeWarning, reasonGatewayGetFailed, "Failed to get Gateway %s/%s, requeued: %s", gatewayNamespace, gatewayName, err)
+		return reconcile.Result{
+			Requeue: true,
+		}, err
+	}
+
+	if certificateInUse {
+		certificateInUse, err = c.isShardInUse(ctx, cert)
+		if err != nil {
+			log.Error(err, "failed to Get sharded Certificate", "certificate", cert.Annotations[v1beta1labels.ShardOfAnnotation])
+			return reconcile.Result{
+				Requeue: true,
+			}, err
+		}
+	}
+
+	// events are recorded on the Gateway, or on the Certificate once the Gateway is gone
+	var eventObject client.Object = cert
+	if gateway != nil {
+		eventObject = gateway
+	}
+
+	if gateway == nil {
 		log.V(1).Info("Gateway not found, marking Certificate for deletion", "gateway-namespace", gatewayNamespace, "gateway", gatewayName)
 		deleteCert = true
-	} else if !isCertificateInGatewaySpec(request.Name, gateway) {
+	} else if !certificateInUse {
 		log.V(1).Info("Matching Tls.CredentialName not found, marking Certificate for deletion", "gateway-namespace", gatewayNamespace, "gateway", gatewayName)
 		deleteCert = true
 	}
 
+	delete(c.retainedCerts, request.String())
+	if retainedBy := retainedBy(cert, gateway); deleteCert && retainedBy != "" {
+		log.Info("Certificate is retained, skipping deletion", "certificate", request.String(), "retained-by", retainedBy)
+		c.retainedCerts[request.String()] = true
+		deleteCert = false
+	}
+	prometheus.UpdateRetainedCertificatesCount(len(c.retainedCerts))
+
+	if !deleteCert {
+		if err := c.clearPendingDeletion(ctx, cert, eventObject); err != nil {
+			log.Error(err, "failed to clear pending deletion of Certificate")
+			return reconcile.Result{
+				Requeue: true,
+			}, err
+		}
+	} else if remaining, err := c.markPendingDeletion(ctx, cert, eventObject); err != nil {
+		log.Error(err, "failed to mark Certificate for deletion")
+		return reconcile.Result{
+			Requeue: true,
+		}, err
+	} else if remaining > 0 {
+		log.V(1).Info("Certificate deletion pending", "certificate", request.String(), "remaining", remaining.String())
+		prometheus.UpdateManagedCertificatesCount(len(c.managedCerts))
+		return reconcile.Result{RequeueAfter: remaining}, nil
+	}
+
 	if deleteCert {
 		log.Info(fmt.Sprintf("Deleting Certificate %s", request), "dry-run", c.dryRun)
 		if err := certIface.Delete(ctx, request.Name, deleteOptions); err != nil {
 			log.Error(err, "failed to Delete Certificate")
+			c.recordEvent(eventObject, corev1.EventTypeWarning, reasonCertificateDeleteFailed, "Failed to delete Certificate %s, requeued: %s", request, err)
 			return reconcile.Result{
 				Requeue: true,
 			}, err
 		}
 
+		c.recordEvent(eventObject, corev1.EventTypeNormal, reasonCertificateDeleted, "Deleted unused Certificate %s", request)
+		c.deleteSecret(ctx, cert, eventObject)
+
 		delete(c.managedCerts, request.String())
 	}
 
@@ -124,6 +276,248 @@ func (c *GarbageCollectionController) Reconcile(ctx context.Context, request rec
 	return reconcile.Result{}, nil
 }
 
+// retainedBy returns the object, the Certificate or its Gateway, whose RetainAnnotation keeps the Certificate
+// from being deleted, or an empty string when the Certificate can be deleted.
+func retainedBy(cert *v1certmanager.Certificate, gateway client.Object) string {
+	if cert.Annotations[v1beta1labels.RetainAnnotation] == "true" {
+		return fmt.Sprintf("Certificate %s/%s", cert.Namespace, cert.Name)
+	}
+
+	if gateway != nil && gateway.GetAnnotations()[v1beta1labels.RetainAnnotation] == "true" {
+		return fmt.Sprintf("Gateway %s/%s", gateway.GetNamespace(), gateway.GetName())
+	}
+
+	return ""
+}
+
+// markPendingDeletion marks an unused Certificate with the time it was first found unused and returns how long
+// remains of the grace period, zero once the Certificate can be deleted.  Without a grace period, or in dry-run mode
+// where the mark cannot be stored, Certificates are deleted immediately.
+func (c *GarbageCollectionController) markPendingDeletion(ctx context.Context, cert *v1certmanager.Certificate, eventObject client.Object) (time.Duration, error) {
+	if c.gracePeriod <= 0 || c.dryRun {
+		return 0, nil
+	}
+
+	if v, ok := cert.Annotations[v1beta1labels.PendingDeletionAnnotation]; ok {
+		if since, err := time.Parse(time.RFC3339, v); err == nil {
+			return max(since.Add(c.gracePeriod).Sub(c.currentTime()), 0), nil
+		}
+	}
+
+	if err := c.patchPendingDeletion(ctx, cert, c.currentTime().UTC().Format(time.RFC3339)); err != nil {
+		return 0, err
+	}
+
+	c.recordEvent(eventObject, corev1.EventTypeNormal, reasonDeletionPending, "Certificate %s/%s is unused, deleting it in %s unless it is used again", cert.Namespace, cert.Name, c.gracePeriod)
+	return c.gracePeriod, nil
+}
+
+func (c *GarbageCollectionController) currentTime() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+
+	return c.now()
+}
+
+// clearPendingDeletion removes the pending deletion mark of a Certificate that is used again.
+func (c *GarbageCollectionController) clearPendingDeletion(ctx context.Context, cert *v1certmanager.Certificate, eventObject client.Object) error {
+	if _, ok := cert.Annotations[v1beta1labels.PendingDeletionAnnotation]; !ok {
+		return nil
+	}
+
+	if err := c.patchPendingDeletion(ctx, cert, nil); err != nil {
+		return err
+	}
+
+	c.recordEvent(eventObject, corev1.EventTypeNormal, reasonDeletionCancelled, "Certificate %s/%s is used again, cancelled its deletion", cert.Namespace, cert.Name)
+	return nil
+}
+
+// patchPendingDeletion sets the PendingDeletionAnnotation, or removes it when value is nil.
+func (c *GarbageCollectionController) patchPendingDeletion(ctx context.Context, cert *v1certmanager.Certificate, value any) error {
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{v1beta1labels.PendingDeletionAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	patchOptions := metav1.PatchOptions{}
+	if c.dryRun {
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	_, err = c.certmanagerClient.CertmanagerV1().Certificates(cert.Namespace).Patch(ctx, cert.Name, types.MergePatchType, patch, patchOptions)
+	return err
+}
+
+// inspectGateway returns the Istio Gateway, nil when it does not exist, and whether it still references the Certificate.
+// With a namespace resolver the Certificate must also be in the namespace of the gateway workload, so Certificates
+// left behind when the Gateway selector moves to another workload are collected.
+func (c *GarbageCollectionController) inspectGateway(ctx context.Context, cert *v1certmanager.Certificate, namespace, name string) (client.Object, bool, error) {
+	log := log.FromContext(ctx)
+
+	gateway, err := c.istioClient.NetworkingV1().Gateways(namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	if !isCertificateInGatewaySpec(credentialName(cert), gateway) {
+		return gateway, false, nil
+	}
+
+	if c.namespaceResolver != nil {
+		workloadNamespace, err := c.namespaceResolver.Namespace(gateway.Spec.Selector)
+		if err != nil {
+			// keep the Certificate until the gateway workload can be resolved
+			log.V(1).Info("failed to resolve gateway workload namespace", "gateway-namespace", namespace, "gateway", name, "error", err.Error())
+			return gateway, true, nil
+		}
+
+		return gateway, workloadNamespace == cert.Namespace, nil
+	}
+
+	return gateway, true, nil
+}
+
+// inspectSharedCertificate inspects every Gateway referencing a shared Certificate and returns the first Gateway
+// still using it, or the first Gateway that exists, and whether any Gateway still uses it.  The references of
+// Gateways that no longer use the Certificate are removed, so it is deleted once the last one is gone.
+func (c *GarbageCollectionController) inspectSharedCertificate(ctx context.Context, cert *v1certmanager.Certificate) (client.Object, bool, error) {
+	var existing, using client.Object
+	refs := v1beta1labels.ParseReferencedBy(cert.Annotations[v1beta1labels.ReferencedByAnnotation])
+	inUse := []string{}
+
+	for _, ref := range refs {
+		name, namespace := v1beta1labels.ParseManagedLabel(ref)
+		if name == "" || namespace == "" {
+			continue
+		}
+
+		gateway, ok, err := c.inspectGateway(ctx, cert, namespace, name)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if gateway != nil && existing == nil {
+			existing = gateway
+		}
+
+		if ok {
+			if using == nil {
+				using = gateway
+			}
+			inUse = append(inUse, ref)
+		}
+	}
+
+	if using == nil {
+		return existing, false, nil
+	}
+
+	if len(inUse) < len(refs) {
+		if err := c.removeReferences(ctx, cert, inUse); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return using, true, nil
+}
+
+// removeReferences keeps only the references of the Gateways still using a shared Certificate.  When the
+// Gateway the Certificate is managed for, which applies it, is removed another Gateway takes over.
+func (c *GarbageCollectionController) removeReferences(ctx context.Context, cert *v1certmanager.Certificate, refs []string) error {
+	log := log.FromContext(ctx)
+
+	annotations := map[string]string{v1beta1labels.ReferencedByAnnotation: strings.Join(refs, ",")}
+	metadata := map[string]any{"annotations": annotations}
+	if name, namespace := v1beta1labels.ManagedGateway(cert.Labels, cert.Annotations); !slices.Contains(refs, v1beta1labels.GatewayReference(name, namespace)) {
+		name, namespace = v1beta1labels.ParseManagedLabel(refs[0])
+		for k, v := range v1beta1labels.ManagedAnnotations(name, namespace) {
+			annotations[k] = v
+		}
+		metadata["labels"] = map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(name, namespace)}
+	}
+
+	patch, err := json.Marshal(map[string]any{"metadata": metadata})
+	if err != nil {
+		return err
+	}
+
+	patchOptions := metav1.PatchOptions{}
+	if c.dryRun {
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	log.Info("Removing unused shared Certificate references", "certificate", fmt.Sprintf("%s/%s", cert.Namespace, cert.Name), "references", refs, "dry-run", c.dryRun)
+	_, err = c.certmanagerClient.CertmanagerV1().Certificates(cert.Namespace).Patch(ctx, cert.Name, types.MergePatchType, patch, patchOptions)
+	return err
+}
+
+// inspectGatewayAPIGateway returns the Gateway API Gateway, nil when it does not exist, and whether it still references the Certificate.
+func (c *GarbageCollectionController) inspectGatewayAPIGateway(ctx context.Context, cert *v1certmanager.Certificate, namespace, name string) (client.Object, bool, error) {
+	gateway, err := c.gatewayAPIClient.GatewayV1beta1().Gateways(namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return gateway, isCertificateInGatewayAPISpec(cert.Name, gateway), nil
+}
+
+// isShardInUse reports whether a shard Certificate is still needed, i.e. its index is below the shard count of
+// the Certificate it was split from.  Certificates that are not shards are always in use.
+func (c *GarbageCollectionController) isShardInUse(ctx context.Context, cert *v1certmanager.Certificate) (bool, error) {
+	name, ok := cert.Annotations[v1beta1labels.ShardOfAnnotation]
+	if !ok {
+		return true, nil
+	}
+
+	index, err := strconv.Atoi(cert.Annotations[v1beta1labels.ShardIndexAnnotation])
+	if err != nil {
+		return false, nil
+	}
+
+	parent, err := c.certmanagerClient.CertmanagerV1().Certificates(cert.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	count, err := strconv.Atoi(parent.Annotations[v1beta1labels.ShardCountAnnotation])
+	if err != nil {
+		return false, nil
+	}
+
+	return index > 0 && index < count, nil
+}
+
+// credentialName returns the credentialName a Certificate is used by, the name of the Certificate a shard was
+// split from or the name of the Certificate itself.
+func credentialName(cert *v1certmanager.Certificate) string {
+	if name, ok := cert.Annotations[v1beta1labels.ShardOfAnnotation]; ok {
+		return name
+	}
+
+	return cert.Name
+}
+
+// recordEvent is a no-op without an event recorder or in dry-run mode.
+func (c *GarbageCollectionController) recordEvent(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if c.recorder == nil || c.dryRun {
+		return
+	}
+
+	c.recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+}
+
 func updateFunc(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
 	q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
 		Name:      e.ObjectNew.GetName(),
@@ -131,11 +525,57 @@ func updateFunc(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateL
 	}})
 }
 
-func isCertificateInGatewaySpec(certificate string, gateway *networkingv1beta1.Gateway) bool {
+// isManagedGateway reports whether the Gateway is labeled for credentialName injection.  The Certificates of a
+// Gateway that is no longer labeled are unowned.
+func isManagedGateway(gateway client.Object) bool {
+	val, ok := gateway.GetLabels()[v1beta1labels.InjectSimpleCredentialNameLabel]
+	return ok && val == "true"
+}
+
+// isManagedServer reports whether the server is given a Certificate, a server that leaves a managed TLS mode, e.g.
+// for PASSTHROUGH, or that is listed as unmanaged on the Gateway, no longer uses its Certificate even if it keeps
+// the credentialName.
+func isManagedServer(gateway client.Object, s *apinetworkingv1.Server) bool {
+	return s.Tls != nil && naming.IsManagedTLSMode(s.Tls.Mode) && !naming.IsUnmanagedServer(gateway, s.Port.GetName(), s.Hosts)
+}
+
+// isManagedListener reports whether the listener terminates TLS and is given a Certificate.
+func isManagedListener(gateway client.Object, l gatewayapiv1beta1.Listener) bool {
+	return l.TLS != nil && (l.TLS.Mode == nil || *l.TLS.Mode == gatewayapiv1beta1.TLSModeTerminate) && !naming.IsUnmanagedListener(gateway, l)
+}
+
+// isCertificateInGatewaySpec reports whether any managed server of a managed Gateway references the Certificate.
+// The admission webhook names credentialNames after the server, the Gateway or the host depending on the
+// certificate granularity, so every granularity is covered by matching the credentialName, including
+// Certificates shared by several servers.
+func isCertificateInGatewaySpec(certificate string, gateway *networkingv1.Gateway) bool {
+	if !isManagedGateway(gateway) {
+		return false
+	}
+
 	for _, s := range gateway.Spec.Servers {
-		if s.Tls != nil && s.Tls.CredentialName == certificate {
+		if isManagedServer(gateway, s) && s.Tls.CredentialName == certificate {
 			return true
 		}
 	}
 	return false
 }
+
+func isCertificateInGatewayAPISpec(certificate string, gateway *gatewayapiv1beta1.Gateway) bool {
+	if !isManagedGateway(gateway) {
+		return false
+	}
+
+	for _, l := range gateway.Spec.Listeners {
+		if !isManagedListener(gateway, l) {
+			continue
+		}
+
+		for _, ref := range l.TLS.CertificateRefs {
+			if ref.Namespace == nil && string(ref.Name) == certificate {
+				return true
+			}
+		}
+	}
+	return false
+}
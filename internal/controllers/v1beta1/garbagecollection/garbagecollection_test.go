@@ -2,21 +2,30 @@ package garbagecollection
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	certmanagerfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
 	"github.com/stretchr/testify/assert"
-	"istio.io/api/networking/v1beta1"
-	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
 	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapifake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
 )
 
+var managedGatewayLabels = map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"}
+
 func TestNewGarbageCollectionController(t *testing.T) {
 	t.Parallel()
 
@@ -30,6 +39,7 @@ func TestNewGarbageCollectionController(t *testing.T) {
 		istioClient:       istioClient,
 		dryRun:            dryRun,
 		managedCerts:      map[string]bool{},
+		retainedCerts:     map[string]bool{},
 	}
 
 	gc := NewGarbageCollectionController(istioClient, certmanagerClient, WithDryRun(dryRun))
@@ -47,20 +57,23 @@ func TestGarbageCollectionControllerReconcile(t *testing.T) {
 		},
 	}
 
-	gatewayWithCert := &networkingv1beta1.Gateway{
+	gatewayWithCert := &networkingv1.Gateway{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "gateway-123",
 			Namespace: "devops",
+			Labels:    managedGatewayLabels,
 		},
-		Spec: v1beta1.Gateway{
-			Servers: []*v1beta1.Server{
+		Spec: apinetworkingv1.Gateway{
+			Servers: []*apinetworkingv1.Server{
 				{
-					Tls: &v1beta1.ServerTLSSettings{
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
 						CredentialName: "devops-gateway-123-diff-cert",
 					},
 				},
 				{
-					Tls: &v1beta1.ServerTLSSettings{
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
 						CredentialName: "devops-gateway-123-cert", // should match certificate name
 					},
 				},
@@ -68,15 +81,17 @@ func TestGarbageCollectionControllerReconcile(t *testing.T) {
 		},
 	}
 
-	gatewayWithoutCert := &networkingv1beta1.Gateway{
+	gatewayWithoutCert := &networkingv1.Gateway{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "gateway-123",
 			Namespace: "devops",
+			Labels:    managedGatewayLabels,
 		},
-		Spec: v1beta1.Gateway{
-			Servers: []*v1beta1.Server{
+		Spec: apinetworkingv1.Gateway{
+			Servers: []*apinetworkingv1.Server{
 				{
-					Tls: &v1beta1.ServerTLSSettings{
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
 						CredentialName: "devops-gateway-123-diff-cert",
 					},
 				},
@@ -84,6 +99,16 @@ func TestGarbageCollectionControllerReconcile(t *testing.T) {
 		},
 	}
 
+	annotatedCertificate := certificate.DeepCopy()
+	annotatedCertificate.Labels = map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue("api.example.com-gw", "devops")}
+	annotatedCertificate.Annotations = v1beta1labels.ManagedAnnotations("api.example.com-gw", "devops")
+
+	legacyDottedCertificate := certificate.DeepCopy()
+	legacyDottedCertificate.Labels = map[string]string{v1beta1labels.ManagedLabel: "api.example.com-gw.devops"}
+
+	dottedGateway := gatewayWithCert.DeepCopy()
+	dottedGateway.Name = "api.example.com-gw"
+
 	reconcileRequest := reconcile.Request{
 		NamespacedName: types.NamespacedName{
 			Namespace: certificate.Namespace,
@@ -94,38 +119,52 @@ func TestGarbageCollectionControllerReconcile(t *testing.T) {
 	tests := []struct {
 		description  string
 		certs        []*v1.Certificate
-		gateways     []*networkingv1beta1.Gateway
+		gateways     []*networkingv1.Gateway
 		wantError    bool
 		wantNumCerts int
 	}{
 		{
 			description:  "Certificate points to existing Gateway, no-op",
 			certs:        []*v1.Certificate{certificate},
-			gateways:     []*networkingv1beta1.Gateway{gatewayWithCert},
+			gateways:     []*networkingv1.Gateway{gatewayWithCert},
 			wantError:    false,
 			wantNumCerts: 1,
 		},
 		{
 			description:  "Certificate points to missing Gateway, delete Certificate",
 			certs:        []*v1.Certificate{certificate},
-			gateways:     []*networkingv1beta1.Gateway{}, // no Gateway
+			gateways:     []*networkingv1.Gateway{}, // no Gateway
 			wantError:    false,
 			wantNumCerts: 0,
 		},
 		{
-			description:  "Reconcile called on a Certificate that doesn't exist anymore",
+			description:  "Reconcile called on a Certificate that doesn't exist anymore, no-op",
 			certs:        []*v1.Certificate{}, // no Certificate
-			gateways:     []*networkingv1beta1.Gateway{},
-			wantError:    true,
+			gateways:     []*networkingv1.Gateway{},
+			wantError:    false,
 			wantNumCerts: 0,
 		},
 		{
 			description:  "Gateway does not contain Certificate, delete Certificate",
 			certs:        []*v1.Certificate{certificate},
-			gateways:     []*networkingv1beta1.Gateway{gatewayWithoutCert},
+			gateways:     []*networkingv1.Gateway{gatewayWithoutCert},
 			wantError:    false,
 			wantNumCerts: 0,
 		},
+		{
+			description:  "Certificate annotated with a Gateway name containing dots, no-op",
+			certs:        []*v1.Certificate{annotatedCertificate},
+			gateways:     []*networkingv1.Gateway{dottedGateway},
+			wantError:    false,
+			wantNumCerts: 1,
+		},
+		{
+			description:  "Legacy label with a Gateway name containing dots, no-op",
+			certs:        []*v1.Certificate{legacyDottedCertificate},
+			gateways:     []*networkingv1.Gateway{dottedGateway},
+			wantError:    false,
+			wantNumCerts: 1,
+		},
 	}
 
 	for _, test := range tests {
@@ -137,7 +176,7 @@ func TestGarbageCollectionControllerReconcile(t *testing.T) {
 			assert.NoError(t, err, test.description)
 		}
 		for _, gateway := range test.gateways {
-			_, err := gc.istioClient.NetworkingV1beta1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+			_, err := gc.istioClient.NetworkingV1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
 			assert.NoError(t, err, test.description)
 		}
 
@@ -195,16 +234,19 @@ func TestUpdateFunc(t *testing.T) {
 func TestIsCertificateInGatewaySpec(t *testing.T) {
 	t.Parallel()
 
-	gateway := &networkingv1beta1.Gateway{
-		Spec: v1beta1.Gateway{
-			Servers: []*v1beta1.Server{
+	gateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Labels: managedGatewayLabels},
+		Spec: apinetworkingv1.Gateway{
+			Servers: []*apinetworkingv1.Server{
 				{
-					Tls: &v1beta1.ServerTLSSettings{
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
 						CredentialName: "some-other-cred",
 					},
 				},
 				{
-					Tls: &v1beta1.ServerTLSSettings{
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
 						CredentialName: "devops-gateway-123-https",
 					},
 				},
@@ -215,12 +257,57 @@ func TestIsCertificateInGatewaySpec(t *testing.T) {
 		},
 	}
 
+	unlabeled := gateway.DeepCopy()
+	unlabeled.Labels = nil
+
+	passthrough := gateway.DeepCopy()
+	passthrough.Spec.Servers[1].Tls.Mode = apinetworkingv1.ServerTLSSettings_PASSTHROUGH
+
+	unmanaged := gateway.DeepCopy()
+	unmanaged.Annotations = map[string]string{v1beta1labels.UnmanagedServersAnnotation: "https"}
+	unmanaged.Spec.Servers[1].Port = &apinetworkingv1.Port{Number: 443, Name: "https"}
+
+	// servers sharing a credentialName with the gateway granularity
+	shared := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Labels: managedGatewayLabels},
+		Spec: apinetworkingv1.Gateway{
+			Servers: []*apinetworkingv1.Server{
+				{
+					Hosts: []string{"a.example.com"},
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
+						CredentialName: "devops-gateway-123",
+					},
+				},
+				{
+					Hosts: []string{"b.example.com"},
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
+						CredentialName: "devops-gateway-123",
+					},
+				},
+			},
+		},
+	}
+
 	tests := []struct {
 		description string
 		certificate string
-		gateway     *networkingv1beta1.Gateway
+		gateway     *networkingv1.Gateway
 		want        bool
 	}{
+		{
+			description: "Certificate shared by several servers",
+			certificate: "devops-gateway-123",
+			gateway:     shared,
+			want:        true,
+		},
+		{
+			description: "Certificate named after a server of a Gateway sharing a Certificate",
+			certificate: "devops-gateway-123-https",
+			gateway:     shared,
+			want:        false,
+		},
 		{
 			description: "Certificate exists in Gateway spec",
 			certificate: "devops-gateway-123-https",
@@ -233,9 +320,551 @@ func TestIsCertificateInGatewaySpec(t *testing.T) {
 			gateway:     gateway,
 			want:        false,
 		},
+		{
+			description: "Gateway is no longer labeled",
+			certificate: "devops-gateway-123-https",
+			gateway:     unlabeled,
+			want:        false,
+		},
+		{
+			description: "server is no longer in a managed TLS mode",
+			certificate: "devops-gateway-123-https",
+			gateway:     passthrough,
+			want:        false,
+		},
+		{
+			description: "server is listed as unmanaged",
+			certificate: "devops-gateway-123-https",
+			gateway:     unmanaged,
+			want:        false,
+		},
 	}
 
 	for _, test := range tests {
 		assert.Equal(t, test.want, isCertificateInGatewaySpec(test.certificate, test.gateway), test.description)
 	}
 }
+
+func TestGarbageCollectionControllerReconcileGatewayAPI(t *testing.T) {
+	t.Parallel()
+
+	certificate := &v1.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "devops-gateway-123-https",
+			Namespace:   "devops",
+			Labels:      map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+			Annotations: map[string]string{v1beta1labels.ManagedGroupAnnotation: gatewayapiv1beta1.GroupName},
+		},
+	}
+
+	gatewayWithListeners := func(refs ...string) *gatewayapiv1beta1.Gateway {
+		gw := &gatewayapiv1beta1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "gateway-123",
+				Namespace: "devops",
+				Labels:    managedGatewayLabels,
+			},
+		}
+		for _, ref := range refs {
+			gw.Spec.Listeners = append(gw.Spec.Listeners, gatewayapiv1beta1.Listener{
+				TLS: &gatewayapiv1beta1.GatewayTLSConfig{
+					CertificateRefs: []gatewayapiv1beta1.SecretObjectReference{{Name: gatewayapiv1beta1.ObjectName(ref)}},
+				},
+			})
+		}
+		return gw
+	}
+
+	reconcileRequest := reconcile.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: certificate.Namespace,
+			Name:      certificate.Name,
+		},
+	}
+
+	tests := []struct {
+		description      string
+		gateway          *gatewayapiv1beta1.Gateway
+		gatewayAPIClient bool
+		wantNumCerts     int
+	}{
+		{
+			description:      "Certificate referenced by a listener, no-op",
+			gateway:          gatewayWithListeners("other", "devops-gateway-123-https"),
+			gatewayAPIClient: true,
+			wantNumCerts:     1,
+		},
+		{
+			description:      "Certificate points to missing Gateway, delete Certificate",
+			gatewayAPIClient: true,
+			wantNumCerts:     0,
+		},
+		{
+			description:      "Gateway does not reference Certificate, delete Certificate",
+			gateway:          gatewayWithListeners("other"),
+			gatewayAPIClient: true,
+			wantNumCerts:     0,
+		},
+		{
+			description:  "Gateway API support disabled, no-op",
+			wantNumCerts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		opts := []OptionsFunc{}
+		gwc := gatewayapifake.NewSimpleClientset()
+		if test.gatewayAPIClient {
+			opts = append(opts, WithGatewayAPIClient(gwc))
+		}
+		if test.gateway != nil {
+			_, err := gwc.GatewayV1beta1().Gateways(test.gateway.Namespace).Create(context.TODO(), test.gateway, metav1.CreateOptions{})
+			assert.NoError(t, err, test.description)
+		}
+
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(certificate.DeepCopy()), opts...)
+
+		r, err := gc.Reconcile(context.TODO(), reconcileRequest)
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, reconcile.Result{}, r, test.description)
+
+		certs, err := gc.certmanagerClient.CertmanagerV1().Certificates(certificate.Namespace).List(context.TODO(), metav1.ListOptions{})
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, test.wantNumCerts, len(certs.Items), test.description)
+	}
+}
+
+func TestGarbageCollectionControllerReconcileRecordsEvents(t *testing.T) {
+	t.Parallel()
+
+	certificate := &v1.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "devops-gateway-123-cert",
+			Namespace: "routing",
+			Labels:    map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+		},
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(certificate), WithEventRecorder(recorder))
+
+	_, err := gc.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: certificate.Name, Namespace: certificate.Namespace}})
+	assert.NoError(t, err)
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, "Normal CertificateDeleted Deleted unused Certificate routing/devops-gateway-123-cert", <-recorder.Events)
+}
+
+func TestGarbageCollectionControllerReconcileGracePeriod(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	pending := func(since time.Duration) map[string]string {
+		return map[string]string{v1beta1labels.PendingDeletionAnnotation: now.Add(-since).Format(time.RFC3339)}
+	}
+
+	gateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-123", Namespace: "devops", Labels: managedGatewayLabels},
+		Spec: apinetworkingv1.Gateway{
+			Servers: []*apinetworkingv1.Server{{Tls: &apinetworkingv1.ServerTLSSettings{Mode: apinetworkingv1.ServerTLSSettings_SIMPLE, CredentialName: "devops-gateway-123-cert"}}},
+		},
+	}
+
+	tests := []struct {
+		description     string
+		annotations     map[string]string
+		gateways        []*networkingv1.Gateway
+		wantResult      reconcile.Result
+		wantDeleted     bool
+		wantPendingFrom string
+		wantEvent       string
+	}{
+		{
+			description:     "unused Certificate is marked for deletion",
+			wantResult:      reconcile.Result{RequeueAfter: 10 * time.Minute},
+			wantPendingFrom: now.Format(time.RFC3339),
+			wantEvent:       "Normal CertificateDeletionPending",
+		},
+		{
+			description:     "marked Certificate is kept during the grace period",
+			annotations:     pending(time.Minute),
+			wantResult:      reconcile.Result{RequeueAfter: 9 * time.Minute},
+			wantPendingFrom: now.Add(-time.Minute).Format(time.RFC3339),
+		},
+		{
+			description: "marked Certificate is deleted after the grace period",
+			annotations: pending(11 * time.Minute),
+			wantDeleted: true,
+			wantEvent:   "Normal CertificateDeleted",
+		},
+		{
+			description:     "invalid mark is replaced",
+			annotations:     map[string]string{v1beta1labels.PendingDeletionAnnotation: "yesterday"},
+			wantResult:      reconcile.Result{RequeueAfter: 10 * time.Minute},
+			wantPendingFrom: now.Format(time.RFC3339),
+			wantEvent:       "Normal CertificateDeletionPending",
+		},
+		{
+			description: "mark is cleared when the Gateway is back",
+			annotations: pending(time.Minute),
+			gateways:    []*networkingv1.Gateway{gateway},
+			wantEvent:   "Normal CertificateDeletionCancelled",
+		},
+	}
+
+	for _, test := range tests {
+		certificate := &v1.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "devops-gateway-123-cert",
+				Namespace:   "routing",
+				Labels:      map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+				Annotations: test.annotations,
+			},
+		}
+
+		recorder := record.NewFakeRecorder(10)
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(certificate), WithGracePeriod(10*time.Minute), WithEventRecorder(recorder))
+		gc.now = func() time.Time { return now }
+
+		for _, gateway := range test.gateways {
+			_, err := gc.istioClient.NetworkingV1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+			assert.NoError(t, err, test.description)
+		}
+
+		r, err := gc.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: certificate.Name, Namespace: certificate.Namespace}})
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, test.wantResult, r, test.description)
+
+		got, err := gc.certmanagerClient.CertmanagerV1().Certificates(certificate.Namespace).Get(context.TODO(), certificate.Name, metav1.GetOptions{})
+		if test.wantDeleted {
+			assert.True(t, k8serrors.IsNotFound(err), test.description)
+		} else {
+			assert.NoError(t, err, test.description)
+			assert.Equal(t, test.wantPendingFrom, got.Annotations[v1beta1labels.PendingDeletionAnnotation], test.description)
+		}
+
+		if test.wantEvent == "" {
+			assert.Len(t, recorder.Events, 0, test.description)
+		} else {
+			assert.Len(t, recorder.Events, 1, test.description)
+			assert.Contains(t, <-recorder.Events, test.wantEvent, test.description)
+		}
+	}
+}
+
+func TestGarbageCollectionControllerReconcileRetain(t *testing.T) {
+	t.Parallel()
+
+	retain := map[string]string{v1beta1labels.RetainAnnotation: "true"}
+
+	gatewayWithoutCert := func(annotations map[string]string) *networkingv1.Gateway {
+		return &networkingv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "gateway-123", Namespace: "devops", Labels: managedGatewayLabels, Annotations: annotations}}
+	}
+
+	tests := []struct {
+		description  string
+		annotations  map[string]string
+		gateways     []*networkingv1.Gateway
+		wantRetained bool
+	}{
+		{
+			description:  "Certificate annotated with retain is kept",
+			annotations:  retain,
+			wantRetained: true,
+		},
+		{
+			description:  "Certificate of a Gateway annotated with retain is kept",
+			gateways:     []*networkingv1.Gateway{gatewayWithoutCert(retain)},
+			wantRetained: true,
+		},
+		{
+			description: "retain annotation must be true",
+			annotations: map[string]string{v1beta1labels.RetainAnnotation: "false"},
+			gateways:    []*networkingv1.Gateway{gatewayWithoutCert(nil)},
+		},
+	}
+
+	for _, test := range tests {
+		certificate := &v1.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "devops-gateway-123-cert",
+				Namespace:   "routing",
+				Labels:      map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+				Annotations: test.annotations,
+			},
+		}
+
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(certificate))
+		for _, gateway := range test.gateways {
+			_, err := gc.istioClient.NetworkingV1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+			assert.NoError(t, err, test.description)
+		}
+
+		r, err := gc.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: certificate.Name, Namespace: certificate.Namespace}})
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, reconcile.Result{}, r, test.description)
+
+		_, err = gc.certmanagerClient.CertmanagerV1().Certificates(certificate.Namespace).Get(context.TODO(), certificate.Name, metav1.GetOptions{})
+		assert.Equal(t, !test.wantRetained, k8serrors.IsNotFound(err), test.description)
+		assert.Equal(t, test.wantRetained, gc.retainedCerts["routing/devops-gateway-123-cert"], test.description)
+	}
+}
+
+func TestGarbageCollectionControllerReconcileCertificateNotFound(t *testing.T) {
+	t.Parallel()
+
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "devops-gateway-123-cert", Namespace: "routing"}}
+
+	gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset())
+	gc.retainedCerts[request.String()] = true
+
+	r, err := gc.Reconcile(context.TODO(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, r)
+	assert.NotContains(t, gc.retainedCerts, request.String())
+	assert.NotContains(t, gc.managedCerts, request.String())
+}
+
+type testNamespaceResolver struct {
+	namespace string
+	err       error
+}
+
+func (r testNamespaceResolver) Namespace(selector map[string]string) (string, error) {
+	return r.namespace, r.err
+}
+
+func TestGarbageCollectionControllerReconcileWorkloadNamespace(t *testing.T) {
+	t.Parallel()
+
+	certificate := &v1.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "devops-gateway-123-cert",
+			Namespace: "istio-ingress",
+			Labels:    map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+		},
+	}
+
+	gateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gateway-123",
+			Namespace: "devops",
+			Labels:    managedGatewayLabels,
+		},
+		Spec: apinetworkingv1.Gateway{
+			Selector: map[string]string{"istio": "ingressgateway"},
+			Servers: []*apinetworkingv1.Server{
+				{
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
+						CredentialName: "devops-gateway-123-cert",
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		description  string
+		resolver     testNamespaceResolver
+		wantNumCerts int
+	}{
+		{
+			description:  "Certificate is in the gateway workload namespace, no-op",
+			resolver:     testNamespaceResolver{namespace: "istio-ingress"},
+			wantNumCerts: 1,
+		},
+		{
+			description:  "Gateway workload moved to another namespace, delete Certificate",
+			resolver:     testNamespaceResolver{namespace: "istio-system"},
+			wantNumCerts: 0,
+		},
+		{
+			description:  "Gateway workload cannot be resolved, keep Certificate",
+			resolver:     testNamespaceResolver{err: fmt.Errorf("no gateway Pods or Services match selector")},
+			wantNumCerts: 1,
+		},
+	}
+
+	for _, test := range tests {
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(certificate.DeepCopy()), WithNamespaceResolver(test.resolver))
+		_, err := gc.istioClient.NetworkingV1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+		assert.NoError(t, err, test.description)
+
+		r, err := gc.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: certificate.Name, Namespace: certificate.Namespace}})
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, reconcile.Result{}, r, test.description)
+
+		certs, err := gc.certmanagerClient.CertmanagerV1().Certificates(certificate.Namespace).List(context.TODO(), metav1.ListOptions{})
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, test.wantNumCerts, len(certs.Items), test.description)
+	}
+}
+
+func TestGarbageCollectionControllerReconcileShards(t *testing.T) {
+	t.Parallel()
+
+	gateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "gateway-123",
+			Namespace: "devops",
+			Labels:    managedGatewayLabels,
+		},
+		Spec: apinetworkingv1.Gateway{
+			Servers: []*apinetworkingv1.Server{
+				{
+					Tls: &apinetworkingv1.ServerTLSSettings{
+						Mode:           apinetworkingv1.ServerTLSSettings_SIMPLE,
+						CredentialName: "devops-gateway-123-cert",
+					},
+				},
+			},
+		},
+	}
+
+	newCertificate := func(name string, annotations map[string]string) *v1.Certificate {
+		return &v1.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "routing",
+				Labels:      map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+				Annotations: annotations,
+			},
+		}
+	}
+
+	shard := func(name string, index string) *v1.Certificate {
+		return newCertificate(fmt.Sprintf("%s-shard-%s", name, index), map[string]string{
+			v1beta1labels.ShardOfAnnotation:    name,
+			v1beta1labels.ShardIndexAnnotation: index,
+		})
+	}
+
+	tests := []struct {
+		description string
+		parent      *v1.Certificate
+		shard       *v1.Certificate
+		wantDeleted bool
+	}{
+		{
+			description: "shard within the shard count, no-op",
+			parent:      newCertificate("devops-gateway-123-cert", map[string]string{v1beta1labels.ShardCountAnnotation: "3"}),
+			shard:       shard("devops-gateway-123-cert", "2"),
+		},
+		{
+			description: "shard beyond the shard count, delete shard",
+			parent:      newCertificate("devops-gateway-123-cert", map[string]string{v1beta1labels.ShardCountAnnotation: "2"}),
+			shard:       shard("devops-gateway-123-cert", "2"),
+			wantDeleted: true,
+		},
+		{
+			description: "Certificate no longer sharded, delete shard",
+			parent:      newCertificate("devops-gateway-123-cert", nil),
+			shard:       shard("devops-gateway-123-cert", "1"),
+			wantDeleted: true,
+		},
+		{
+			description: "parent Certificate not found, delete shard",
+			parent:      newCertificate("other", nil),
+			shard:       shard("devops-gateway-123-cert", "1"),
+			wantDeleted: true,
+		},
+		{
+			description: "credentialName removed from the Gateway, delete shard",
+			parent:      newCertificate("devops-gateway-123-other", map[string]string{v1beta1labels.ShardCountAnnotation: "2"}),
+			shard:       shard("devops-gateway-123-other", "1"),
+			wantDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(test.parent, test.shard))
+		_, err := gc.istioClient.NetworkingV1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+		assert.NoError(t, err, test.description)
+
+		_, err = gc.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: test.shard.Name, Namespace: test.shard.Namespace}})
+		assert.NoError(t, err, test.description)
+
+		_, err = gc.certmanagerClient.CertmanagerV1().Certificates(test.shard.Namespace).Get(context.TODO(), test.shard.Name, metav1.GetOptions{})
+		assert.Equal(t, test.wantDeleted, k8serrors.IsNotFound(err), test.description)
+	}
+}
+
+func TestGarbageCollectionControllerReconcileSharedCertificate(t *testing.T) {
+	t.Parallel()
+
+	const name = "shared-0123456789abcdef0123"
+
+	newGateway := func(gateway, credentialName string) *networkingv1.Gateway {
+		return &networkingv1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{Name: gateway, Namespace: "devops", Labels: managedGatewayLabels},
+			Spec: apinetworkingv1.Gateway{
+				Servers: []*apinetworkingv1.Server{
+					{Tls: &apinetworkingv1.ServerTLSSettings{Mode: apinetworkingv1.ServerTLSSettings_SIMPLE, CredentialName: credentialName}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		description   string
+		gateways      []*networkingv1.Gateway
+		wantDeleted   bool
+		wantRefs      string
+		wantManagedBy string
+	}{
+		{
+			description:   "referenced by both Gateways, no-op",
+			gateways:      []*networkingv1.Gateway{newGateway("blue", name), newGateway("green", name)},
+			wantRefs:      "blue.devops,green.devops",
+			wantManagedBy: "blue.devops",
+		},
+		{
+			description:   "labeled Gateway deleted, remove its reference",
+			gateways:      []*networkingv1.Gateway{newGateway("green", name)},
+			wantRefs:      "green.devops",
+			wantManagedBy: "green.devops",
+		},
+		{
+			description:   "Gateway no longer references the Certificate, remove its reference",
+			gateways:      []*networkingv1.Gateway{newGateway("blue", name), newGateway("green", "devops-green-https")},
+			wantRefs:      "blue.devops",
+			wantManagedBy: "blue.devops",
+		},
+		{
+			description: "no Gateway references the Certificate, delete",
+			gateways:    []*networkingv1.Gateway{newGateway("blue", "devops-blue-https")},
+			wantDeleted: true,
+		},
+		{
+			description: "all Gateways deleted, delete",
+			wantDeleted: true,
+		},
+	}
+
+	for _, test := range tests {
+		cert := &v1.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "routing",
+				Labels:      map[string]string{v1beta1labels.ManagedLabel: "blue.devops"},
+				Annotations: map[string]string{v1beta1labels.ReferencedByAnnotation: "blue.devops,green.devops"},
+			},
+		}
+
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(cert))
+		for _, gateway := range test.gateways {
+			_, err := gc.istioClient.NetworkingV1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+			assert.NoError(t, err, test.description)
+		}
+
+		_, err := gc.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "routing"}})
+		assert.NoError(t, err, test.description)
+
+		got, err := gc.certmanagerClient.CertmanagerV1().Certificates("routing").Get(context.TODO(), name, metav1.GetOptions{})
+		assert.Equal(t, test.wantDeleted, k8serrors.IsNotFound(err), test.description)
+		if !test.wantDeleted {
+			assert.Equal(t, test.wantRefs, got.Annotations[v1beta1labels.ReferencedByAnnotation], test.description)
+			assert.Equal(t, test.wantManagedBy, v1beta1labels.GatewayReference(v1beta1labels.ManagedGateway(got.Labels, got.Annotations)), test.description)
+		}
+	}
+}
@@ -1,9 +1,76 @@
 package garbagecollection
 
+import (
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	gatewayapiclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+)
+
 type OptionsFunc func(*GarbageCollectionController)
 
+// NamespaceResolver resolves an Istio Gateway selector to the namespace of the gateway workload.
+type NamespaceResolver interface {
+	Namespace(selector map[string]string) (string, error)
+}
+
 func WithDryRun(dryrun bool) OptionsFunc {
 	return func(gcc *GarbageCollectionController) {
 		gcc.dryRun = dryrun
 	}
 }
+
+// WithGatewayAPIClient enables garbage collection of Certificates managed for Gateway API Gateways.
+func WithGatewayAPIClient(client gatewayapiclient.Interface) OptionsFunc {
+	return func(gcc *GarbageCollectionController) {
+		gcc.gatewayAPIClient = client
+	}
+}
+
+// WithEventRecorder records Kubernetes Events for deleted Certificates and failures.
+func WithEventRecorder(r record.EventRecorder) OptionsFunc {
+	return func(gcc *GarbageCollectionController) {
+		gcc.recorder = r
+	}
+}
+
+// WithNamespaceResolver deletes Istio Gateway Certificates that are not in the namespace of the gateway workload,
+// matching the Gateway controller when Certificates are placed in the workload namespace.
+func WithNamespaceResolver(r NamespaceResolver) OptionsFunc {
+	return func(gcc *GarbageCollectionController) {
+		gcc.namespaceResolver = r
+	}
+}
+
+// WithGracePeriod marks unused Certificates for deletion and only deletes them once they are still unused after
+// the grace period, so a Gateway that is deleted and recreated keeps its Certificate.  Zero deletes immediately.
+func WithGracePeriod(d time.Duration) OptionsFunc {
+	return func(gcc *GarbageCollectionController) {
+		gcc.gracePeriod = d
+	}
+}
+
+// WithSecretDeletion deletes the TLS Secret of a garbage collected Certificate, which cert-manager keeps by
+// default.  With WithSecretSweepInterval it also sweeps the Secrets of managed Certificates that no longer exist.
+func WithSecretDeletion(client kubernetes.Interface) OptionsFunc {
+	return func(gcc *GarbageCollectionController) {
+		gcc.coreClient = client
+	}
+}
+
+// WithSecretSweepInterval lists the TLS Secrets of managed Certificates at the interval, with WithSecretDeletion,
+// and deletes the Secrets whose Certificate no longer exists.  Zero disables it.
+func WithSecretSweepInterval(d time.Duration) OptionsFunc {
+	return func(gcc *GarbageCollectionController) {
+		gcc.secretSweepInterval = d
+	}
+}
+
+// WithSweepInterval lists every managed Certificate and Gateway at the interval, reports the Certificates no
+// Gateway uses and enqueues them, including Certificates created before the controller started.  Zero disables it.
+func WithSweepInterval(d time.Duration) OptionsFunc {
+	return func(gcc *GarbageCollectionController) {
+		gcc.sweepInterval = d
+	}
+}
@@ -0,0 +1,120 @@
+package garbagecollection
+
+import (
+	"context"
+	"fmt"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+const (
+	reasonSecretDeleted      = "SecretDeleted"
+	reasonSecretDeleteFailed = "SecretDeleteFailed"
+)
+
+// isCertificateSecret reports whether the Secret was issued by cert-manager for the named Certificate, so Secrets
+// written by others under the same name are never deleted.
+func isCertificateSecret(secret *corev1.Secret, certificate string) bool {
+	return secret.Type == corev1.SecretTypeTLS && secret.Annotations[v1certmanager.CertificateNameKey] == certificate
+}
+
+// deleteSecret deletes the Secret of a deleted Certificate.  Failures are logged and reported but not requeued,
+// the Certificate is gone and the Secret sweep removes the Secret later.
+func (c *GarbageCollectionController) deleteSecret(ctx context.Context, cert *v1certmanager.Certificate, eventObject client.Object) {
+	log := log.FromContext(ctx)
+
+	if c.coreClient == nil || cert.Spec.SecretName == "" {
+		return
+	}
+
+	secrets := c.coreClient.CoreV1().Secrets(cert.Namespace)
+	secret, err := secrets.Get(ctx, cert.Spec.SecretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return
+	} else if err != nil {
+		log.Error(err, "failed to Get Certificate Secret", "secret", cert.Spec.SecretName)
+		return
+	}
+
+	if !isCertificateSecret(secret, cert.Name) {
+		log.V(1).Info("Secret was not issued for the Certificate, skipping", "secret", secret.Name, "certificate", cert.Name)
+		return
+	}
+
+	if err := c.deleteSecretObject(ctx, secret); err != nil {
+		log.Error(err, "failed to Delete Certificate Secret", "secret", secret.Name)
+		c.recordEvent(eventObject, corev1.EventTypeWarning, reasonSecretDeleteFailed, "Failed to delete Secret %s/%s of Certificate %s: %s", secret.Namespace, secret.Name, cert.Name, err)
+		return
+	}
+
+	c.recordEvent(eventObject, corev1.EventTypeNormal, reasonSecretDeleted, "Deleted Secret %s/%s of Certificate %s", secret.Namespace, secret.Name, cert.Name)
+}
+
+// sweepSecrets deletes the TLS Secrets issued for managed Certificates that no longer exist, e.g. Certificates
+// deleted while the controller was not running or before Secret deletion was enabled.  Only Secrets with the
+// ManagedLabel, which managed Certificates add through their secretTemplate, are swept, so Secrets left behind by
+// Certificates of others are kept.  Secrets are listed before Certificates, so the Secret of a Certificate created
+// during the sweep is kept.
+func (c *GarbageCollectionController) sweepSecrets(ctx context.Context) {
+	log := log.FromContext(ctx)
+
+	secrets, err := c.coreClient.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: v1beta1labels.ManagedLabelSelector(),
+		FieldSelector: fields.OneTermEqualSelector("type", string(corev1.SecretTypeTLS)).String(),
+	})
+	if err != nil {
+		log.Error(err, "failed to List managed Secrets")
+		return
+	}
+
+	certs, err := c.certmanagerClient.CertmanagerV1().Certificates(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Error(err, "failed to List Certificates")
+		return
+	}
+
+	existing := sets.New[types.NamespacedName]()
+	for _, cert := range certs.Items {
+		existing.Insert(types.NamespacedName{Namespace: cert.Namespace, Name: cert.Name})
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+
+		name, ok := secret.Annotations[v1certmanager.CertificateNameKey]
+		if !ok || !isCertificateSecret(secret, name) || existing.Has(types.NamespacedName{Namespace: secret.Namespace, Name: name}) {
+			continue
+		}
+
+		if err := c.deleteSecretObject(ctx, secret); err != nil {
+			log.Error(err, "failed to Delete orphaned Secret", "secret", fmt.Sprintf("%s/%s", secret.Namespace, secret.Name))
+		}
+	}
+}
+
+func (c *GarbageCollectionController) deleteSecretObject(ctx context.Context, secret *corev1.Secret) error {
+	deleteOptions := metav1.DeleteOptions{}
+	if c.dryRun {
+		deleteOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Deleting Secret %s/%s", secret.Namespace, secret.Name), "dry-run", c.dryRun)
+	return c.coreClient.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, deleteOptions)
+}
+
+// runSecretSweep sweeps orphaned Secrets every secretSweepInterval until the context is cancelled.
+func (c *GarbageCollectionController) runSecretSweep(ctx context.Context) error {
+	wait.UntilWithContext(ctx, c.sweepSecrets, c.secretSweepInterval)
+	return nil
+}
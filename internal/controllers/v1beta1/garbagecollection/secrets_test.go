@@ -0,0 +1,130 @@
+package garbagecollection
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanagerfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestSecret(name, certificate string, secretType corev1.SecretType) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "routing",
+			Labels:      map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue("gateway-123", "devops")},
+			Annotations: map[string]string{v1.CertificateNameKey: certificate},
+		},
+		Type: secretType,
+	}
+}
+
+func TestGarbageCollectionControllerReconcileDeletesSecret(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		secret      *corev1.Secret
+		wantDeleted bool
+		wantEvent   string
+	}{
+		{
+			description: "Secret issued for the Certificate is deleted",
+			secret:      newTestSecret("devops-gateway-123-cert", "devops-gateway-123-cert", corev1.SecretTypeTLS),
+			wantDeleted: true,
+			wantEvent:   "Normal SecretDeleted",
+		},
+		{
+			description: "Secret issued for another Certificate is kept",
+			secret:      newTestSecret("devops-gateway-123-cert", "other-cert", corev1.SecretTypeTLS),
+		},
+		{
+			description: "Secret that is not a TLS Secret is kept",
+			secret:      newTestSecret("devops-gateway-123-cert", "devops-gateway-123-cert", corev1.SecretTypeOpaque),
+		},
+	}
+
+	for _, test := range tests {
+		certificate := &v1.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "devops-gateway-123-cert",
+				Namespace: "routing",
+				Labels:    map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+			},
+			Spec: v1.CertificateSpec{SecretName: "devops-gateway-123-cert"},
+		}
+
+		coreClient := k8sfake.NewClientset(test.secret)
+		recorder := record.NewFakeRecorder(10)
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(certificate),
+			WithSecretDeletion(coreClient), WithEventRecorder(recorder))
+
+		_, err := gc.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: certificate.Name, Namespace: certificate.Namespace}})
+		assert.NoError(t, err, test.description)
+
+		_, err = coreClient.CoreV1().Secrets(test.secret.Namespace).Get(context.TODO(), test.secret.Name, metav1.GetOptions{})
+		assert.Equal(t, test.wantDeleted, k8serrors.IsNotFound(err), test.description)
+
+		<-recorder.Events // CertificateDeleted
+		if test.wantEvent == "" {
+			assert.Len(t, recorder.Events, 0, test.description)
+		} else {
+			assert.Contains(t, <-recorder.Events, test.wantEvent, test.description)
+		}
+	}
+}
+
+func TestSweepSecrets(t *testing.T) {
+	t.Parallel()
+
+	certificate := &v1.Certificate{ObjectMeta: metav1.ObjectMeta{Name: "in-use", Namespace: "routing"}}
+
+	// an orphaned cert-manager Secret of a Certificate this controller did not manage
+	unlabeled := newTestSecret("unlabeled", "unlabeled", corev1.SecretTypeTLS)
+	unlabeled.Labels = nil
+
+	unannotated := newTestSecret("unannotated", "unannotated", corev1.SecretTypeTLS)
+	unannotated.Annotations = nil
+
+	// the Secret of a managed Certificate placed in a workload namespace
+	otherNamespace := newTestSecret("in-use", "in-use", corev1.SecretTypeTLS)
+	otherNamespace.Namespace = "devops"
+
+	secrets := []*corev1.Secret{
+		newTestSecret("in-use", "in-use", corev1.SecretTypeTLS),
+		newTestSecret("orphaned", "orphaned", corev1.SecretTypeTLS),
+		newTestSecret("orphaned-cacert", "orphaned", corev1.SecretTypeOpaque),
+		unlabeled,
+		unannotated,
+		otherNamespace,
+	}
+
+	coreClient := k8sfake.NewClientset()
+	for _, secret := range secrets {
+		_, err := coreClient.CoreV1().Secrets(secret.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(certificate), WithSecretDeletion(coreClient))
+	gc.sweepSecrets(context.TODO())
+
+	got, err := coreClient.CoreV1().Secrets(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, secret := range got.Items {
+		names = append(names, secret.Namespace+"/"+secret.Name)
+	}
+	assert.ElementsMatch(t, []string{"routing/in-use", "routing/orphaned-cacert", "routing/unannotated", "routing/unlabeled"}, names)
+}
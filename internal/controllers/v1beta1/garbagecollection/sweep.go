@@ -0,0 +1,174 @@
+package garbagecollection
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/kanopy-platform/gateway-certificate-controller/internal/prometheus"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanagerlisters "github.com/cert-manager/cert-manager/pkg/client/listers/certmanager/v1"
+)
+
+// certificatesForGateway enqueues the Certificates managed for, or shared with, a Gateway that is deleted or
+// that may no longer use them, i.e. its inject label or its spec changed, so they are collected without waiting
+// for the next Certificate resync.
+func certificatesForGateway(lister certmanagerlisters.CertificateLister) handler.Funcs {
+	enqueue := func(ctx context.Context, gateway client.Object, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		certs, err := lister.List(labels.Everything())
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to List managed Certificates of Gateway", "gateway-namespace", gateway.GetNamespace(), "gateway", gateway.GetName())
+			return
+		}
+
+		ref := v1beta1labels.GatewayReference(gateway.GetName(), gateway.GetNamespace())
+		for _, cert := range certs {
+			if slices.Contains(v1beta1labels.ManagingGateways(cert.Labels, cert.Annotations), ref) {
+				q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: cert.Name, Namespace: cert.Namespace}})
+			}
+		}
+	}
+
+	return handler.Funcs{
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if isManagedGateway(e.ObjectOld) == isManagedGateway(e.ObjectNew) && e.ObjectOld.GetGeneration() == e.ObjectNew.GetGeneration() {
+				return
+			}
+
+			enqueue(ctx, e.ObjectNew, q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, e.Object, q)
+		},
+	}
+}
+
+// findOrphanedCertificates lists every managed Certificate and every Gateway once and returns the Certificates
+// that no managed server or listener of a labeled Gateway references by credentialName or certificateRef.
+// Reconcile makes the final decision, including shards, workload namespaces, retention and the grace period.
+func (c *GarbageCollectionController) findOrphanedCertificates(ctx context.Context) ([]*v1certmanager.Certificate, error) {
+	certs, err := c.certmanagerClient.CertmanagerV1().Certificates(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: v1beta1labels.ManagedLabelSelector()})
+	if err != nil {
+		return nil, err
+	}
+
+	gateways, err := c.istioClient.NetworkingV1().Gateways(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := map[string]bool{}
+	for _, gateway := range gateways.Items {
+		if !isManagedGateway(gateway) {
+			continue
+		}
+
+		for _, s := range gateway.Spec.Servers {
+			if isManagedServer(gateway, s) && s.Tls.CredentialName != "" {
+				inUse[usageKey("", gateway.Namespace, gateway.Name, s.Tls.CredentialName)] = true
+			}
+		}
+	}
+
+	if c.gatewayAPIClient != nil {
+		gateways, err := c.gatewayAPIClient.GatewayV1beta1().Gateways(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range gateways.Items {
+			gateway := &gateways.Items[i]
+			if !isManagedGateway(gateway) {
+				continue
+			}
+
+			for _, l := range gateway.Spec.Listeners {
+				if !isManagedListener(gateway, l) {
+					continue
+				}
+
+				for _, ref := range l.TLS.CertificateRefs {
+					if ref.Namespace == nil {
+						inUse[usageKey(gatewayapiv1beta1.GroupName, gateway.Namespace, gateway.Name, string(ref.Name))] = true
+					}
+				}
+			}
+		}
+	}
+
+	orphans := []*v1certmanager.Certificate{}
+	for i := range certs.Items {
+		cert := &certs.Items[i]
+
+		group := cert.Annotations[v1beta1labels.ManagedGroupAnnotation]
+		if group == gatewayapiv1beta1.GroupName && c.gatewayAPIClient == nil {
+			continue
+		}
+
+		used := false
+		for _, ref := range v1beta1labels.ManagingGateways(cert.Labels, cert.Annotations) {
+			name, namespace := v1beta1labels.ParseManagedLabel(ref)
+			if inUse[usageKey(group, namespace, name, credentialName(cert))] {
+				used = true
+				break
+			}
+		}
+
+		if !used {
+			orphans = append(orphans, cert)
+		}
+	}
+
+	return orphans, nil
+}
+
+// usageKey identifies the use of a credentialName, or certificateRef, by a Gateway.
+func usageKey(group, namespace, name, credential string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", group, namespace, name, credential)
+}
+
+// sweepCertificates reports the orphaned Certificates found in a single pass and enqueues them for Reconcile.
+func (c *GarbageCollectionController) sweepCertificates(ctx context.Context) {
+	log := log.FromContext(ctx)
+
+	orphans, err := c.findOrphanedCertificates(ctx)
+	if err != nil {
+		log.Error(err, "garbage collection sweep failed")
+		return
+	}
+
+	names := make([]string, 0, len(orphans))
+	for _, cert := range orphans {
+		names = append(names, fmt.Sprintf("%s/%s", cert.Namespace, cert.Name))
+	}
+
+	log.Info("Garbage collection sweep completed", "orphaned", len(orphans), "certificates", names)
+	prometheus.UpdateOrphanedCertificatesCount(len(orphans))
+
+	for _, cert := range orphans {
+		select {
+		case c.sweepEvents <- event.GenericEvent{Object: cert}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runCertificateSweep sweeps managed Certificates at the sweep interval until the context is cancelled.
+func (c *GarbageCollectionController) runCertificateSweep(ctx context.Context) error {
+	wait.UntilWithContext(ctx, c.sweepCertificates, c.sweepInterval)
+	return nil
+}
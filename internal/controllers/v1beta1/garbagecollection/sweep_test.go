@@ -0,0 +1,177 @@
+package garbagecollection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanagerfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	certmanagerlisters "github.com/cert-manager/cert-manager/pkg/client/listers/certmanager/v1"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapifake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
+)
+
+func newSweepTestCertificate(name string, labels, annotations map[string]string) *v1.Certificate {
+	return &v1.Certificate{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "routing", Labels: labels, Annotations: annotations}}
+}
+
+func TestCertificatesForGateway(t *testing.T) {
+	t.Parallel()
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, cert := range []*v1.Certificate{
+		newSweepTestCertificate("annotated", map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue("gateway-123", "devops")}, v1beta1labels.ManagedAnnotations("gateway-123", "devops")),
+		newSweepTestCertificate("legacy", map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"}, nil),
+		newSweepTestCertificate("shared", map[string]string{v1beta1labels.ManagedLabel: "other.devops"}, map[string]string{v1beta1labels.ReferencedByAnnotation: "gateway-123.devops,other.devops"}),
+		newSweepTestCertificate("other", map[string]string{v1beta1labels.ManagedLabel: "other.devops"}, nil),
+	} {
+		assert.NoError(t, indexer.Add(cert))
+	}
+
+	gateway := &networkingv1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "gateway-123", Namespace: "devops", Labels: managedGatewayLabels, Generation: 1}}
+	unlabeled := gateway.DeepCopy()
+	unlabeled.Labels = nil
+	changed := gateway.DeepCopy()
+	changed.Generation = 2
+
+	tests := []struct {
+		description string
+		enqueue     func(handler.Funcs, workqueue.TypedRateLimitingInterface[reconcile.Request])
+		want        []string
+	}{
+		{
+			description: "deleted Gateway",
+			enqueue: func(h handler.Funcs, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				h.Delete(context.TODO(), event.DeleteEvent{Object: gateway}, q)
+			},
+			want: []string{"annotated", "legacy", "shared"},
+		},
+		{
+			description: "unlabeled Gateway",
+			enqueue: func(h handler.Funcs, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				h.Update(context.TODO(), event.UpdateEvent{ObjectOld: gateway, ObjectNew: unlabeled}, q)
+			},
+			want: []string{"annotated", "legacy", "shared"},
+		},
+		{
+			description: "Gateway spec changed",
+			enqueue: func(h handler.Funcs, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				h.Update(context.TODO(), event.UpdateEvent{ObjectOld: gateway, ObjectNew: changed}, q)
+			},
+			want: []string{"annotated", "legacy", "shared"},
+		},
+		{
+			description: "Gateway status or metadata changed",
+			enqueue: func(h handler.Funcs, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				h.Update(context.TODO(), event.UpdateEvent{ObjectOld: gateway, ObjectNew: gateway.DeepCopy()}, q)
+			},
+			want: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		test.enqueue(certificatesForGateway(certmanagerlisters.NewCertificateLister(indexer)), q)
+
+		got := []string{}
+		for q.Len() > 0 {
+			r, _ := q.Get()
+			got = append(got, r.Name)
+			q.Done(r)
+		}
+		assert.ElementsMatch(t, test.want, got, test.description)
+		q.ShutDown()
+	}
+}
+
+func TestFindOrphanedCertificates(t *testing.T) {
+	t.Parallel()
+
+	gatewayAPIAnnotations := map[string]string{v1beta1labels.ManagedGroupAnnotation: gatewayapiv1beta1.GroupName}
+	certs := []*v1.Certificate{
+		newSweepTestCertificate("in-use", map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"}, nil),
+		newSweepTestCertificate("renamed-port", map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"}, nil),
+		newSweepTestCertificate("deleted-gateway", map[string]string{v1beta1labels.ManagedLabel: "deleted.devops"}, nil),
+		newSweepTestCertificate("shared", map[string]string{v1beta1labels.ManagedLabel: "deleted.devops"}, map[string]string{v1beta1labels.ReferencedByAnnotation: "deleted.devops,gateway-123.devops"}),
+		newSweepTestCertificate("gateway-api", map[string]string{v1beta1labels.ManagedLabel: "web.devops"}, gatewayAPIAnnotations),
+		newSweepTestCertificate("gateway-api-deleted", map[string]string{v1beta1labels.ManagedLabel: "deleted.devops"}, gatewayAPIAnnotations),
+	}
+
+	istioGateway := &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-123", Namespace: "devops", Labels: managedGatewayLabels},
+		Spec: apinetworkingv1.Gateway{Servers: []*apinetworkingv1.Server{
+			{Tls: &apinetworkingv1.ServerTLSSettings{Mode: apinetworkingv1.ServerTLSSettings_SIMPLE, CredentialName: "in-use"}},
+			{Tls: &apinetworkingv1.ServerTLSSettings{Mode: apinetworkingv1.ServerTLSSettings_SIMPLE, CredentialName: "shared"}},
+		}},
+	}
+
+	gatewayAPIGateway := &gatewayapiv1beta1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "devops", Labels: managedGatewayLabels},
+		Spec: gatewayapiv1beta1.GatewaySpec{Listeners: []gatewayapiv1beta1.Listener{{
+			TLS: &gatewayapiv1beta1.GatewayTLSConfig{CertificateRefs: []gatewayapiv1beta1.SecretObjectReference{{Name: "gateway-api"}}},
+		}}},
+	}
+
+	tests := []struct {
+		description string
+		gatewayAPI  bool
+		want        []string
+	}{
+		{description: "Istio Gateways", want: []string{"renamed-port", "deleted-gateway"}},
+		{description: "with Gateway API Gateways", gatewayAPI: true, want: []string{"renamed-port", "deleted-gateway", "gateway-api-deleted"}},
+	}
+
+	for _, test := range tests {
+		opts := []OptionsFunc{}
+		if test.gatewayAPI {
+			gwc := gatewayapifake.NewSimpleClientset()
+			_, err := gwc.GatewayV1beta1().Gateways(gatewayAPIGateway.Namespace).Create(context.TODO(), gatewayAPIGateway, metav1.CreateOptions{})
+			assert.NoError(t, err, test.description)
+			opts = append(opts, WithGatewayAPIClient(gwc))
+		}
+
+		gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(), opts...)
+		_, err := gc.istioClient.NetworkingV1().Gateways(istioGateway.Namespace).Create(context.TODO(), istioGateway, metav1.CreateOptions{})
+		assert.NoError(t, err, test.description)
+		for _, cert := range certs {
+			_, err := gc.certmanagerClient.CertmanagerV1().Certificates(cert.Namespace).Create(context.TODO(), cert, metav1.CreateOptions{})
+			assert.NoError(t, err, test.description)
+		}
+
+		orphans, err := gc.findOrphanedCertificates(context.TODO())
+		assert.NoError(t, err, test.description)
+
+		got := []string{}
+		for _, cert := range orphans {
+			got = append(got, cert.Name)
+		}
+		assert.ElementsMatch(t, test.want, got, test.description)
+	}
+}
+
+func TestSweepCertificatesEnqueuesOrphans(t *testing.T) {
+	t.Parallel()
+
+	cert := newSweepTestCertificate("deleted-gateway", map[string]string{v1beta1labels.ManagedLabel: "deleted.devops"}, nil)
+	gc := NewGarbageCollectionController(istiofake.NewSimpleClientset(), certmanagerfake.NewSimpleClientset(cert), WithSweepInterval(time.Minute))
+	gc.sweepEvents = make(chan event.GenericEvent, 1)
+
+	gc.sweepCertificates(context.TODO())
+
+	assert.Len(t, gc.sweepEvents, 1)
+	e := <-gc.sweepEvents
+	assert.Equal(t, types.NamespacedName{Name: "deleted-gateway", Namespace: "routing"}, types.NamespacedName{Name: e.Object.GetName(), Namespace: e.Object.GetNamespace()})
+}
@@ -0,0 +1,95 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kanopy-platform/gateway-certificate-controller/internal/prometheus"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+// AdoptionPolicy selects what the controllers do with an existing Certificate, named like a computed
+// credentialName, that does not have the ManagedLabel, i.e. it was created by a user or another tool.
+type AdoptionPolicy string
+
+const (
+	// AdoptionPolicyRefuse leaves unmanaged Certificates alone and reports the conflict on the Gateway.
+	AdoptionPolicyRefuse AdoptionPolicy = "refuse"
+	// AdoptionPolicyAdoptWithLabel takes over unmanaged Certificates, applying the ManagedLabel, hosts and issuer.
+	AdoptionPolicyAdoptWithLabel AdoptionPolicy = "adopt-with-label"
+	// AdoptionPolicyAdoptIfHostsMatch takes over unmanaged Certificates that already hold exactly the hosts of
+	// the Gateway, and refuses the others.
+	AdoptionPolicyAdoptIfHostsMatch AdoptionPolicy = "adopt-if-hosts-match"
+
+	reasonCertificateAdopted         = "CertificateAdopted"
+	reasonCertificateAdoptionRefused = "CertificateAdoptionRefused"
+)
+
+// ParseAdoptionPolicy returns the AdoptionPolicy named by s.
+func ParseAdoptionPolicy(s string) (AdoptionPolicy, error) {
+	switch p := AdoptionPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case AdoptionPolicyRefuse, AdoptionPolicyAdoptWithLabel, AdoptionPolicyAdoptIfHostsMatch:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid certificate adoption policy %q, must be one of: %s, %s, %s", s, AdoptionPolicyRefuse, AdoptionPolicyAdoptWithLabel, AdoptionPolicyAdoptIfHostsMatch)
+	}
+}
+
+// isManagedCertificate reports whether a Certificate has the ManagedLabel, including the legacy label value.
+func isManagedCertificate(cert *v1certmanager.Certificate) bool {
+	_, ok := cert.Labels[v1beta1labels.ManagedLabel]
+	return ok
+}
+
+// adoptsCertificate reports whether the desired Certificate may be applied over the current one.  Managed
+// Certificates are always applied, unmanaged Certificates only as allowed by the adoption policy.  Refusals are
+// reported with an Event on the Gateway and counted once per Certificate in the certificate_adoptions_refused_total
+// metric.
+func (c *config) adoptsCertificate(ctx context.Context, gateway client.Object, desired, current *v1certmanager.Certificate) bool {
+	if isManagedCertificate(current) {
+		return true
+	}
+
+	log := log.FromContext(ctx)
+
+	switch c.adoptionPolicy {
+	case AdoptionPolicyAdoptWithLabel:
+		c.recordEvent(gateway, corev1.EventTypeNormal, reasonCertificateAdopted, "Adopted unmanaged Certificate %s/%s", current.Namespace, current.Name)
+		return true
+	case AdoptionPolicyAdoptIfHostsMatch:
+		if certificateHostsMatch(desired, current) {
+			c.recordEvent(gateway, corev1.EventTypeNormal, reasonCertificateAdopted, "Adopted unmanaged Certificate %s/%s with matching hosts", current.Namespace, current.Name)
+			return true
+		}
+
+		log.Info("refusing to adopt unmanaged certificate with different hosts", "cert", current.Name, "namespace", current.Namespace)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateAdoptionRefused, "Certificate %s/%s is not managed by the controller and its hosts differ from the Gateway, it was left unchanged", current.Namespace, current.Name)
+	default:
+		log.Info("refusing to adopt unmanaged certificate", "cert", current.Name, "namespace", current.Namespace)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateAdoptionRefused, "Certificate %s/%s is not managed by the controller, it was left unchanged", current.Namespace, current.Name)
+	}
+
+	prometheus.IncrementCertificateAdoptionsRefused(current.UID)
+	return false
+}
+
+// certificateHostsMatch reports whether two Certificates hold the same DNS names and IP addresses, ignoring
+// order and case.
+func certificateHostsMatch(desired, current *v1certmanager.Certificate) bool {
+	hosts := func(cert *v1certmanager.Certificate) sets.Set[string] {
+		s := sets.New[string]()
+		for _, h := range append(append([]string{}, cert.Spec.DNSNames...), cert.Spec.IPAddresses...) {
+			s.Insert(strings.ToLower(strings.TrimSuffix(h, ".")))
+		}
+		return s
+	}
+
+	return hosts(desired).Equal(hosts(current))
+}
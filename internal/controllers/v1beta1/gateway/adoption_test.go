@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+func TestParseAdoptionPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, p := range []AdoptionPolicy{AdoptionPolicyRefuse, AdoptionPolicyAdoptWithLabel, AdoptionPolicyAdoptIfHostsMatch} {
+		got, err := ParseAdoptionPolicy(" " + string(p) + " ")
+		assert.NoError(t, err)
+		assert.Equal(t, p, got)
+	}
+
+	_, err := ParseAdoptionPolicy("adopt")
+	assert.Error(t, err)
+}
+
+func TestGatewayReconcile_AdoptionPolicy(t *testing.T) {
+	t.Parallel()
+
+	unmanaged := func(dnsNames ...string) *v1certmanager.Certificate {
+		return &v1certmanager.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      TestCertificateName,
+				Namespace: TestCertNamespace,
+				Labels:    map[string]string{"team": "platform"},
+			},
+			Spec: v1certmanager.CertificateSpec{
+				DNSNames:   dnsNames,
+				SecretName: TestCertificateName,
+				IssuerRef:  v1.ObjectReference{Kind: "Issuer", Name: "team-issuer"},
+			},
+		}
+	}
+
+	tests := []struct {
+		description string
+		policy      AdoptionPolicy
+		cert        *v1certmanager.Certificate
+		wantAdopted bool
+		wantEvent   string
+	}{
+		{
+			description: "refused by default",
+			cert:        unmanaged("test1.example.com", "test2.example.com"),
+			wantEvent:   "Warning CertificateAdoptionRefused",
+		},
+		{
+			description: "refused",
+			policy:      AdoptionPolicyRefuse,
+			cert:        unmanaged("test1.example.com", "test2.example.com"),
+			wantEvent:   "Warning CertificateAdoptionRefused",
+		},
+		{
+			description: "adopted with label",
+			policy:      AdoptionPolicyAdoptWithLabel,
+			cert:        unmanaged("other.example.com"),
+			wantAdopted: true,
+			wantEvent:   "Normal CertificateAdopted",
+		},
+		{
+			description: "adopted with matching hosts",
+			policy:      AdoptionPolicyAdoptIfHostsMatch,
+			cert:        unmanaged("Test2.example.com", "test1.example.com"),
+			wantAdopted: true,
+			wantEvent:   "Normal CertificateAdopted",
+		},
+		{
+			description: "refused with different hosts",
+			policy:      AdoptionPolicyAdoptIfHostsMatch,
+			cert:        unmanaged("test1.example.com"),
+			wantEvent:   "Warning CertificateAdoptionRefused",
+		},
+	}
+
+	for _, test := range tests {
+		helper := NewTestHelperWithGateways(AppendCertificates(test.cert))
+		WithAdoptionPolicy(test.policy)(&helper.Controller.config)
+		recorder := record.NewFakeRecorder(10)
+		helper.Controller.recorder = recorder
+
+		_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, 1, helper.Controller.UpdateCalled, test.description)
+
+		cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+		assert.NoError(t, err, test.description)
+		if test.wantAdopted {
+			assert.Equal(t, v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace), cert.Labels[v1beta1labels.ManagedLabel], test.description)
+			assert.Equal(t, "default", cert.Spec.IssuerRef.Name, test.description)
+		} else {
+			assert.Equal(t, test.cert, cert, test.description)
+		}
+
+		assert.Contains(t, <-recorder.Events, test.wantEvent, test.description)
+	}
+}
@@ -0,0 +1,244 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+const (
+	// cert-manager rejects Certificates with a shorter duration or renewBefore
+	minimumDuration    = time.Hour
+	minimumRenewBefore = 5 * time.Minute
+)
+
+var (
+	validKeyUsages = map[v1certmanager.KeyUsage]bool{
+		v1certmanager.UsageSigning:           true,
+		v1certmanager.UsageDigitalSignature:  true,
+		v1certmanager.UsageContentCommitment: true,
+		v1certmanager.UsageKeyEncipherment:   true,
+		v1certmanager.UsageKeyAgreement:      true,
+		v1certmanager.UsageDataEncipherment:  true,
+		v1certmanager.UsageCertSign:          true,
+		v1certmanager.UsageCRLSign:           true,
+		v1certmanager.UsageEncipherOnly:      true,
+		v1certmanager.UsageDecipherOnly:      true,
+		v1certmanager.UsageAny:               true,
+		v1certmanager.UsageServerAuth:        true,
+		v1certmanager.UsageClientAuth:        true,
+		v1certmanager.UsageCodeSigning:       true,
+		v1certmanager.UsageEmailProtection:   true,
+		v1certmanager.UsageSMIME:             true,
+		v1certmanager.UsageIPsecEndSystem:    true,
+		v1certmanager.UsageIPsecTunnel:       true,
+		v1certmanager.UsageIPsecUser:         true,
+		v1certmanager.UsageTimestamping:      true,
+		v1certmanager.UsageOCSPSigning:       true,
+		v1certmanager.UsageMicrosoftSGC:      true,
+		v1certmanager.UsageNetscapeSGC:       true,
+	}
+
+	validKeySizes = map[v1certmanager.PrivateKeyAlgorithm][]int{
+		v1certmanager.RSAKeyAlgorithm:   {2048, 4096, 8192},
+		v1certmanager.ECDSAKeyAlgorithm: {256, 384, 521},
+	}
+)
+
+// updateCertificateOverrides sets the Certificate spec fields that Gateway owners may override with
+// annotations.  A missing annotation leaves the field unset so cert-manager applies its default, an invalid
+// annotation is logged and the field keeps its value from the current Certificate, if any.
+func (c *config) updateCertificateOverrides(ctx context.Context, cert, current *v1certmanager.Certificate, gateway client.Object) *v1certmanager.Certificate {
+	log := log.FromContext(ctx)
+
+	base := cert.Spec
+	if current != nil {
+		base = current.Spec
+	}
+
+	spec, err := applyCertificateSpecAnnotations(base, gateway.GetAnnotations())
+	if err != nil {
+		log.Error(err, "invalid certificate annotations on gateway", "gateway", gateway.GetName(), "namespace", gateway.GetNamespace())
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonInvalidAnnotations, "Invalid Certificate annotations: %s", err)
+	}
+
+	cert.Spec.Duration = spec.Duration
+	cert.Spec.RenewBefore = spec.RenewBefore
+	cert.Spec.PrivateKey = spec.PrivateKey
+	cert.Spec.Usages = spec.Usages
+	cert.Spec.Subject = spec.Subject
+	return cert
+}
+
+// applyCertificateSpecAnnotations returns a copy of spec with the overridable fields set from annotations.
+// Fields whose annotation fails validation keep their value from spec.
+func applyCertificateSpecAnnotations(spec v1certmanager.CertificateSpec, annotations map[string]string) (v1certmanager.CertificateSpec, error) {
+	out := *spec.DeepCopy()
+	errs := []error{}
+
+	if duration, err := parseDurationAnnotation(annotations, v1beta1labels.DurationAnnotation, minimumDuration); err != nil {
+		errs = append(errs, err)
+	} else {
+		out.Duration = duration
+	}
+
+	if renewBefore, err := parseDurationAnnotation(annotations, v1beta1labels.RenewBeforeAnnotation, minimumRenewBefore); err != nil {
+		errs = append(errs, err)
+	} else if renewBefore != nil && out.Duration != nil && renewBefore.Duration >= out.Duration.Duration {
+		errs = append(errs, fmt.Errorf("%s must be less than %s", v1beta1labels.RenewBeforeAnnotation, v1beta1labels.DurationAnnotation))
+	} else {
+		out.RenewBefore = renewBefore
+	}
+
+	if privateKey, err := parsePrivateKeyAnnotations(annotations, out.PrivateKey); err != nil {
+		errs = append(errs, err)
+	} else {
+		out.PrivateKey = privateKey
+	}
+
+	if usages, err := parseUsagesAnnotation(annotations); err != nil {
+		errs = append(errs, err)
+	} else {
+		out.Usages = usages
+	}
+
+	if subject, err := parseSubjectAnnotation(annotations); err != nil {
+		errs = append(errs, err)
+	} else {
+		out.Subject = subject
+	}
+
+	return out, utilerrors.NewAggregate(errs)
+}
+
+func parseDurationAnnotation(annotations map[string]string, key string, minimum time.Duration) (*metav1.Duration, error) {
+	v, ok := annotations[key]
+	if !ok || strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+
+	if d < minimum {
+		return nil, fmt.Errorf("%s: %s is less than the minimum of %s", key, d, minimum)
+	}
+
+	return &metav1.Duration{Duration: d}, nil
+}
+
+// parsePrivateKeyAnnotations builds the private key settings from annotations.  The encoding is not
+// configurable and is carried over from the current settings.
+func parsePrivateKeyAnnotations(annotations map[string]string, current *v1certmanager.CertificatePrivateKey) (*v1certmanager.CertificatePrivateKey, error) {
+	algorithm := strings.TrimSpace(annotations[v1beta1labels.PrivateKeyAlgorithmAnnotation])
+	size := strings.TrimSpace(annotations[v1beta1labels.PrivateKeySizeAnnotation])
+	rotationPolicy := strings.TrimSpace(annotations[v1beta1labels.PrivateKeyRotationPolicyAnnotation])
+
+	pk := &v1certmanager.CertificatePrivateKey{}
+	if current != nil {
+		pk.Encoding = current.Encoding
+	}
+
+	if algorithm == "" && size == "" && rotationPolicy == "" {
+		if pk.Encoding == "" {
+			return nil, nil
+		}
+		return pk, nil
+	}
+
+	switch a := v1certmanager.PrivateKeyAlgorithm(algorithm); a {
+	case "", v1certmanager.RSAKeyAlgorithm, v1certmanager.ECDSAKeyAlgorithm, v1certmanager.Ed25519KeyAlgorithm:
+		pk.Algorithm = a
+	default:
+		return nil, fmt.Errorf("%s: unsupported algorithm %q", v1beta1labels.PrivateKeyAlgorithmAnnotation, algorithm)
+	}
+
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v1beta1labels.PrivateKeySizeAnnotation, err)
+		}
+
+		// cert-manager defaults the algorithm to RSA
+		a := pk.Algorithm
+		if a == "" {
+			a = v1certmanager.RSAKeyAlgorithm
+		}
+
+		if a != v1certmanager.Ed25519KeyAlgorithm && !slices.Contains(validKeySizes[a], n) {
+			return nil, fmt.Errorf("%s: invalid size %d for %s keys", v1beta1labels.PrivateKeySizeAnnotation, n, a)
+		}
+
+		pk.Size = n
+	}
+
+	switch p := v1certmanager.PrivateKeyRotationPolicy(rotationPolicy); p {
+	case "", v1certmanager.RotationPolicyNever, v1certmanager.RotationPolicyAlways:
+		pk.RotationPolicy = p
+	default:
+		return nil, fmt.Errorf("%s: unsupported rotation policy %q", v1beta1labels.PrivateKeyRotationPolicyAnnotation, rotationPolicy)
+	}
+
+	return pk, nil
+}
+
+// parseUsagesAnnotation parses a comma separated list of key usages, e.g. "digital signature,key encipherment,server auth".
+func parseUsagesAnnotation(annotations map[string]string) ([]v1certmanager.KeyUsage, error) {
+	v, ok := annotations[v1beta1labels.UsagesAnnotation]
+	if !ok || strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+
+	usages := []v1certmanager.KeyUsage{}
+	seen := map[v1certmanager.KeyUsage]bool{}
+
+	for _, u := range strings.Split(v, ",") {
+		usage := v1certmanager.KeyUsage(strings.ToLower(strings.TrimSpace(u)))
+		if usage == "" || seen[usage] {
+			continue
+		}
+
+		if !validKeyUsages[usage] {
+			return nil, fmt.Errorf("%s: unsupported usage %q", v1beta1labels.UsagesAnnotation, usage)
+		}
+
+		seen[usage] = true
+		usages = append(usages, usage)
+	}
+
+	return usages, nil
+}
+
+// parseSubjectAnnotation parses a JSON encoded cert-manager X509Subject, e.g. {"organizations":["Example Inc"]}.
+func parseSubjectAnnotation(annotations map[string]string) (*v1certmanager.X509Subject, error) {
+	v, ok := annotations[v1beta1labels.SubjectAnnotation]
+	if !ok || strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+
+	subject := &v1certmanager.X509Subject{}
+	decoder := json.NewDecoder(bytes.NewBufferString(v))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(subject); err != nil {
+		return nil, fmt.Errorf("%s: %w", v1beta1labels.SubjectAnnotation, err)
+	}
+
+	return subject, nil
+}
@@ -0,0 +1,166 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+func TestApplyCertificateSpecAnnotations(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		spec        v1certmanager.CertificateSpec
+		annotations map[string]string
+		want        v1certmanager.CertificateSpec
+		wantErr     bool
+	}{
+		{
+			description: "no annotations",
+			want:        v1certmanager.CertificateSpec{},
+		},
+		{
+			description: "all annotations",
+			annotations: map[string]string{
+				v1beta1labels.DurationAnnotation:                 "2160h",
+				v1beta1labels.RenewBeforeAnnotation:              "360h",
+				v1beta1labels.PrivateKeyAlgorithmAnnotation:      "ECDSA",
+				v1beta1labels.PrivateKeySizeAnnotation:           "384",
+				v1beta1labels.PrivateKeyRotationPolicyAnnotation: "Always",
+				v1beta1labels.UsagesAnnotation:                   "Digital Signature, key encipherment,server auth,server auth",
+				v1beta1labels.SubjectAnnotation:                  `{"organizations":["Example Inc"],"countries":["US"]}`,
+			},
+			want: v1certmanager.CertificateSpec{
+				Duration:    &metav1.Duration{Duration: 2160 * time.Hour},
+				RenewBefore: &metav1.Duration{Duration: 360 * time.Hour},
+				PrivateKey: &v1certmanager.CertificatePrivateKey{
+					Algorithm:      v1certmanager.ECDSAKeyAlgorithm,
+					Size:           384,
+					RotationPolicy: v1certmanager.RotationPolicyAlways,
+				},
+				Usages: []v1certmanager.KeyUsage{
+					v1certmanager.UsageDigitalSignature,
+					v1certmanager.UsageKeyEncipherment,
+					v1certmanager.UsageServerAuth,
+				},
+				Subject: &v1certmanager.X509Subject{
+					Organizations: []string{"Example Inc"},
+					Countries:     []string{"US"},
+				},
+			},
+		},
+		{
+			description: "removed annotations revert the fields",
+			spec: v1certmanager.CertificateSpec{
+				Duration:   &metav1.Duration{Duration: 2160 * time.Hour},
+				PrivateKey: &v1certmanager.CertificatePrivateKey{Algorithm: v1certmanager.RSAKeyAlgorithm, Size: 4096},
+				Usages:     []v1certmanager.KeyUsage{v1certmanager.UsageServerAuth},
+				Subject:    &v1certmanager.X509Subject{Organizations: []string{"Example Inc"}},
+			},
+			want: v1certmanager.CertificateSpec{},
+		},
+		{
+			description: "private key encoding is preserved",
+			spec: v1certmanager.CertificateSpec{
+				PrivateKey: &v1certmanager.CertificatePrivateKey{Encoding: v1certmanager.PKCS8, Size: 4096},
+			},
+			want: v1certmanager.CertificateSpec{
+				PrivateKey: &v1certmanager.CertificatePrivateKey{Encoding: v1certmanager.PKCS8},
+			},
+		},
+		{
+			description: "invalid annotations keep the current values",
+			spec: v1certmanager.CertificateSpec{
+				Duration: &metav1.Duration{Duration: 2160 * time.Hour},
+				Usages:   []v1certmanager.KeyUsage{v1certmanager.UsageServerAuth},
+			},
+			annotations: map[string]string{
+				v1beta1labels.DurationAnnotation:    "10m",
+				v1beta1labels.RenewBeforeAnnotation: "360h",
+				v1beta1labels.UsagesAnnotation:      "server auth,bogus",
+			},
+			want: v1certmanager.CertificateSpec{
+				Duration:    &metav1.Duration{Duration: 2160 * time.Hour},
+				RenewBefore: &metav1.Duration{Duration: 360 * time.Hour},
+				Usages:      []v1certmanager.KeyUsage{v1certmanager.UsageServerAuth},
+			},
+			wantErr: true,
+		},
+		{
+			description: "renewBefore must be less than duration",
+			annotations: map[string]string{
+				v1beta1labels.DurationAnnotation:    "24h",
+				v1beta1labels.RenewBeforeAnnotation: "48h",
+			},
+			want: v1certmanager.CertificateSpec{
+				Duration: &metav1.Duration{Duration: 24 * time.Hour},
+			},
+			wantErr: true,
+		},
+		{
+			description: "rsa key size is validated against the default algorithm",
+			annotations: map[string]string{v1beta1labels.PrivateKeySizeAnnotation: "384"},
+			want:        v1certmanager.CertificateSpec{},
+			wantErr:     true,
+		},
+		{
+			description: "unsupported algorithm",
+			annotations: map[string]string{v1beta1labels.PrivateKeyAlgorithmAnnotation: "DSA"},
+			want:        v1certmanager.CertificateSpec{},
+			wantErr:     true,
+		},
+		{
+			description: "unsupported rotation policy",
+			annotations: map[string]string{v1beta1labels.PrivateKeyRotationPolicyAnnotation: "Sometimes"},
+			want:        v1certmanager.CertificateSpec{},
+			wantErr:     true,
+		},
+		{
+			description: "unknown subject field",
+			annotations: map[string]string{v1beta1labels.SubjectAnnotation: `{"organisation":["Example Inc"]}`},
+			want:        v1certmanager.CertificateSpec{},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		spec, err := applyCertificateSpecAnnotations(test.spec, test.annotations)
+		assert.Equal(t, test.want, spec, test.description)
+		if test.wantErr {
+			assert.Error(t, err, test.description)
+		} else {
+			assert.NoError(t, err, test.description)
+		}
+	}
+}
+
+func TestGatewayReconcile_CreateCertificateWithSpecOverrides(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithGateways(WithAnnotations(map[string]string{
+		v1beta1labels.DurationAnnotation:            "720h",
+		v1beta1labels.PrivateKeyAlgorithmAnnotation: "Ed25519",
+	}))
+	assertCreateCertificateCalled(t, helper)
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, &metav1.Duration{Duration: 720 * time.Hour}, cert.Spec.Duration)
+	assert.Equal(t, v1certmanager.Ed25519KeyAlgorithm, cert.Spec.PrivateKey.Algorithm)
+}
+
+func TestGatewayReconcile_UpdatesCertificateWithSpecOverrides(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithCertificates(WithAnnotations(map[string]string{
+		v1beta1labels.RenewBeforeAnnotation: "240h",
+	}))
+	assertCertificateUpdated(t, helper)
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, &metav1.Duration{Duration: 240 * time.Hour}, cert.Spec.RenewBefore)
+}
@@ -0,0 +1,192 @@
+package gateway
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+const (
+	clientCASecretKey = "cacert"
+
+	defaultClientCAKey = "ca.crt"
+
+	reasonClientCAApplied     = "ClientCAApplied"
+	reasonClientCAApplyFailed = "ClientCAApplyFailed"
+)
+
+// clientCASource is a ConfigMap or Secret key in the namespace of the Gateway holding a PEM encoded CA bundle.
+type clientCASource struct {
+	kind string
+	name string
+	key  string
+}
+
+// parseClientCAAnnotation parses "<ConfigMap|Secret>/<name>[/<key>]", the key defaults to ca.crt.
+func parseClientCAAnnotation(v string) (clientCASource, error) {
+	parts := strings.Split(strings.TrimSpace(v), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] == "" {
+		return clientCASource{}, fmt.Errorf("%s: %q must be <ConfigMap|Secret>/<name>[/<key>]", v1beta1labels.ClientCAAnnotation, v)
+	}
+
+	source := clientCASource{kind: parts[0], name: parts[1], key: defaultClientCAKey}
+	if len(parts) == 3 && parts[2] != "" {
+		source.key = parts[2]
+	}
+
+	if source.kind != "ConfigMap" && source.kind != "Secret" {
+		return clientCASource{}, fmt.Errorf("%s: unsupported kind %q, must be ConfigMap or Secret", v1beta1labels.ClientCAAnnotation, source.kind)
+	}
+
+	return source, nil
+}
+
+// reconcileClientCA copies the client CA bundle selected by the Gateway annotation into the "<credentialName>-cacert"
+// Secret next to the Certificate of a mutual TLS server.  The Secret is owned by the Certificate, so it is deleted
+// along with it.  Without the annotation a previously managed Secret is deleted, leaving Istio to use the ca.crt
+// key cert-manager writes into the credentialName Secret.
+func (c *GatewayController) reconcileClientCA(ctx context.Context, gateway *networkingv1.Gateway, server *apinetworkingv1.Server, cert *v1certmanager.Certificate) error {
+	if !naming.IsMutualTLSMode(server.Tls.Mode) {
+		return nil
+	}
+
+	if c.coreClient == nil {
+		return fmt.Errorf("a kubernetes client is required to manage client CA Secrets")
+	}
+
+	log := log.FromContext(ctx)
+	name := cert.Name + naming.ClientCASecretSuffix
+	secrets := c.coreClient.CoreV1().Secrets(cert.Namespace)
+
+	// Istio only reads the client CA from exactly this name, which the webhook keeps within the limit
+	if len(name) > naming.SecretNameMaxLength {
+		err := fmt.Errorf("client CA Secret name %s is longer than %d characters", name, naming.SecretNameMaxLength)
+		log.Error(err, "skipping client CA secret, the credentialName is too long", "cert", cert.Name, "namespace", cert.Namespace)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonClientCAApplyFailed, "Failed to apply client CA Secret for Certificate %s/%s: %s, shorten the credentialName", cert.Namespace, cert.Name, err)
+		return nil
+	}
+
+	v, ok := gateway.Annotations[v1beta1labels.ClientCAAnnotation]
+	if !ok {
+		return c.deleteClientCA(ctx, cert.Namespace, name)
+	}
+
+	bundle, err := c.clientCABundle(ctx, gateway.Namespace, v)
+	if err != nil {
+		log.Error(err, "invalid client CA", "gateway", gateway.Name, "namespace", gateway.Namespace)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonClientCAApplyFailed, "Failed to read client CA for Secret %s/%s: %s", cert.Namespace, name, err)
+		return err
+	}
+
+	applyOptions := metav1.ApplyOptions{FieldManager: FieldManager, Force: true}
+	if c.dryRun {
+		log.Info("[dryrun] apply client CA secret", "secret", name, "namespace", cert.Namespace)
+		applyOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	secret := corev1ac.Secret(name, cert.Namespace).
+		WithLabels(map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(gateway.Name, gateway.Namespace)}).
+		WithAnnotations(v1beta1labels.ManagedAnnotations(gateway.Name, gateway.Namespace)).
+		WithOwnerReferences(metav1ac.OwnerReference().
+			WithAPIVersion(v1certmanager.SchemeGroupVersion.String()).
+			WithKind(v1certmanager.CertificateKind).
+			WithName(cert.Name).
+			WithUID(cert.UID)).
+		WithType(corev1.SecretTypeOpaque).
+		WithData(map[string][]byte{clientCASecretKey: bundle})
+
+	current, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	exists := err == nil
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	if _, err := secrets.Apply(ctx, secret, applyOptions); err != nil {
+		log.Error(err, "error on client CA secret apply", "secret", name, "namespace", cert.Namespace)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonClientCAApplyFailed, "Failed to apply client CA Secret %s/%s: %s", cert.Namespace, name, err)
+		return err
+	}
+
+	if !exists || string(current.Data[clientCASecretKey]) != string(bundle) {
+		c.recordEvent(gateway, corev1.EventTypeNormal, reasonClientCAApplied, "Applied client CA Secret %s/%s from %s", cert.Namespace, name, v)
+	}
+
+	return nil
+}
+
+// clientCABundle reads and validates the CA bundle referenced by the annotation value.
+func (c *GatewayController) clientCABundle(ctx context.Context, namespace, annotation string) ([]byte, error) {
+	source, err := parseClientCAAnnotation(annotation)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle []byte
+	switch source.kind {
+	case "ConfigMap":
+		cm, err := c.coreClient.CoreV1().ConfigMaps(namespace).Get(ctx, source.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		if v, ok := cm.Data[source.key]; ok {
+			bundle = []byte(v)
+		} else {
+			bundle = cm.BinaryData[source.key]
+		}
+	case "Secret":
+		secret, err := c.coreClient.CoreV1().Secrets(namespace).Get(ctx, source.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		bundle = secret.Data[source.key]
+	}
+
+	if block, _ := pem.Decode(bundle); block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s/%s key %s does not contain a PEM encoded certificate", source.kind, source.name, source.key)
+	}
+
+	return bundle, nil
+}
+
+// deleteClientCA deletes a client CA Secret managed by the controller, Secrets created by others are left alone.
+func (c *GatewayController) deleteClientCA(ctx context.Context, namespace, name string) error {
+	secrets := c.coreClient.CoreV1().Secrets(namespace)
+
+	secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, ok := secret.Labels[v1beta1labels.ManagedLabel]; !ok {
+		return nil
+	}
+
+	deleteOptions := metav1.DeleteOptions{}
+	if c.dryRun {
+		deleteOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	if err := secrets.Delete(ctx, name, deleteOptions); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "istio.io/api/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+const TestMutualCertificateName = "mygateway-mtls"
+
+var testCABundle = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("test-ca")})
+
+func appendMutualServer(mode networkingv1.ServerTLSSettings_TLSmode) func(*GatewayOptions) {
+	return AppendServer(&networkingv1.Server{
+		Hosts: []string{"mtls.example.com"},
+		Tls: &networkingv1.ServerTLSSettings{
+			CredentialName: TestMutualCertificateName,
+			Mode:           mode,
+		},
+	})
+}
+
+func newTestHelperWithClientCA(objs []runtime.Object, opts ...func(*GatewayOptions)) (*TestHelper, *k8sfake.Clientset) {
+	helper := NewTestHelperWithGateways(opts...)
+	coreClient := k8sfake.NewClientset(objs...)
+	helper.Controller.coreClient = coreClient
+	return helper, coreClient
+}
+
+func TestParseClientCAAnnotation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    clientCASource
+		wantErr bool
+	}{
+		{value: "ConfigMap/client-ca", want: clientCASource{kind: "ConfigMap", name: "client-ca", key: "ca.crt"}},
+		{value: "Secret/client-ca/bundle.pem", want: clientCASource{kind: "Secret", name: "client-ca", key: "bundle.pem"}},
+		{value: "client-ca", wantErr: true},
+		{value: "ConfigMap/", wantErr: true},
+		{value: "Pod/client-ca", wantErr: true},
+		{value: "Secret/client-ca/ca.crt/extra", wantErr: true},
+	}
+
+	for _, test := range tests {
+		source, err := parseClientCAAnnotation(test.value)
+		assert.Equal(t, test.want, source, test.value)
+		if test.wantErr {
+			assert.Error(t, err, test.value)
+		} else {
+			assert.NoError(t, err, test.value)
+		}
+	}
+}
+
+func TestGatewayReconcile_CreatesCertificateForMutualServers(t *testing.T) {
+	t.Parallel()
+
+	for _, mode := range []networkingv1.ServerTLSSettings_TLSmode{networkingv1.ServerTLSSettings_MUTUAL, networkingv1.ServerTLSSettings_OPTIONAL_MUTUAL} {
+		helper, _ := newTestHelperWithClientCA(nil, appendMutualServer(mode))
+		_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+		assert.NoError(t, err, mode.String())
+
+		cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestMutualCertificateName, metav1.GetOptions{})
+		assert.NoError(t, err, mode.String())
+		assert.Equal(t, []string{"mtls.example.com"}, cert.Spec.DNSNames, mode.String())
+	}
+}
+
+func TestGatewayReconcile_AppliesClientCASecret(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		annotation  string
+		source      runtime.Object
+	}{
+		{
+			description: "ConfigMap",
+			annotation:  "ConfigMap/client-ca",
+			source: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "client-ca", Namespace: TestNamespace},
+				Data:       map[string]string{"ca.crt": string(testCABundle)},
+			},
+		},
+		{
+			description: "Secret with a key",
+			annotation:  "Secret/client-ca/bundle.pem",
+			source: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "client-ca", Namespace: TestNamespace},
+				Data:       map[string][]byte{"bundle.pem": testCABundle},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		helper, coreClient := newTestHelperWithClientCA([]runtime.Object{test.source},
+			appendMutualServer(networkingv1.ServerTLSSettings_MUTUAL),
+			WithAnnotations(map[string]string{v1beta1labels.ClientCAAnnotation: test.annotation}))
+
+		_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+		assert.NoError(t, err, test.description)
+
+		secret, err := coreClient.CoreV1().Secrets(TestCertNamespace).Get(context.TODO(), TestMutualCertificateName+"-cacert", metav1.GetOptions{})
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, testCABundle, secret.Data["cacert"], test.description)
+		assert.Equal(t, v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace), secret.Labels[v1beta1labels.ManagedLabel], test.description)
+		assert.Equal(t, TestGatewayName, secret.Annotations[v1beta1labels.ManagedNameAnnotation], test.description)
+		assert.Len(t, secret.OwnerReferences, 1, test.description)
+		assert.Equal(t, "Certificate", secret.OwnerReferences[0].Kind, test.description)
+		assert.Equal(t, TestMutualCertificateName, secret.OwnerReferences[0].Name, test.description)
+
+		// no client CA Secret for the SIMPLE server
+		_, err = coreClient.CoreV1().Secrets(TestCertNamespace).Get(context.TODO(), TestCertificateName+"-cacert", metav1.GetOptions{})
+		assert.True(t, k8serrors.IsNotFound(err), test.description)
+	}
+}
+
+func TestGatewayReconcile_InvalidClientCARequeues(t *testing.T) {
+	t.Parallel()
+
+	helper, _ := newTestHelperWithClientCA([]runtime.Object{&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "client-ca", Namespace: TestNamespace},
+		Data:       map[string]string{"ca.crt": "not a certificate"},
+	}}, appendMutualServer(networkingv1.ServerTLSSettings_MUTUAL), WithAnnotations(map[string]string{v1beta1labels.ClientCAAnnotation: "ConfigMap/client-ca"}))
+
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.Error(t, err)
+}
+
+func TestGatewayReconcile_DeletesManagedClientCASecret(t *testing.T) {
+	t.Parallel()
+
+	managed := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      TestMutualCertificateName + "-cacert",
+		Namespace: TestCertNamespace,
+		Labels:    map[string]string{v1beta1labels.ManagedLabel: "mygateway.test"},
+	}}
+
+	helper, coreClient := newTestHelperWithClientCA([]runtime.Object{managed}, appendMutualServer(networkingv1.ServerTLSSettings_MUTUAL))
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	_, err = coreClient.CoreV1().Secrets(TestCertNamespace).Get(context.TODO(), managed.Name, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	// Secrets created by others are left alone
+	unmanaged := managed.DeepCopy()
+	unmanaged.Labels = nil
+	helper, coreClient = newTestHelperWithClientCA([]runtime.Object{unmanaged}, appendMutualServer(networkingv1.ServerTLSSettings_MUTUAL))
+	_, err = helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	_, err = coreClient.CoreV1().Secrets(TestCertNamespace).Get(context.TODO(), unmanaged.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+func TestGatewayReconcile_SkipsClientCASecretOfLongCredentialName(t *testing.T) {
+	t.Parallel()
+
+	for _, length := range []int{naming.CredentialNameMaxLength, naming.SecretNameMaxLength} {
+		name := strings.Repeat("a", length)
+		helper, coreClient := newTestHelperWithClientCA([]runtime.Object{&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "client-ca", Namespace: TestNamespace},
+			Data:       map[string]string{"ca.crt": string(testCABundle)},
+		}}, AppendServer(&networkingv1.Server{
+			Hosts: []string{"mtls.example.com"},
+			Tls:   &networkingv1.ServerTLSSettings{CredentialName: name, Mode: networkingv1.ServerTLSSettings_MUTUAL},
+		}), WithAnnotations(map[string]string{v1beta1labels.ClientCAAnnotation: "ConfigMap/client-ca"}))
+
+		_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+		assert.NoError(t, err, length)
+
+		_, err = coreClient.CoreV1().Secrets(TestCertNamespace).Get(context.TODO(), name+naming.ClientCASecretSuffix, metav1.GetOptions{})
+		assert.Equal(t, length == naming.CredentialNameMaxLength, err == nil, length)
+	}
+}
@@ -2,67 +2,78 @@ package gateway
 
 import (
 	"context"
-	"fmt"
-	"reflect"
-	"sort"
-	"strings"
+	"encoding/json"
+	"strconv"
 	"time"
 
-	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/cache"
 	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
 
 	certmanagerclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
-	"istio.io/api/networking/v1beta1"
-	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	certmanagerv1client "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/typed/certmanager/v1"
+	certmanagerinformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
 	istioversionedclient "istio.io/client-go/pkg/clientset/versioned"
 	istioinformers "istio.io/client-go/pkg/informers/externalversions"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	k8scache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/csaupgrade"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
-	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
 )
 
 const (
 	FieldManager = "isto-cert-controller"
+
+	defaultIssuerKind  = naming.DefaultIssuerKind
+	defaultIssuerGroup = naming.DefaultIssuerGroup
+
+	reasonCertificateCreated      = "CertificateCreated"
+	reasonCertificateCreateFailed = "CertificateCreateFailed"
+	reasonCertificateUpdated      = "CertificateUpdated"
+	reasonCertificateUpdateFailed = "CertificateUpdateFailed"
+	reasonCertificateGetFailed    = "CertificateGetFailed"
+	reasonInvalidAnnotations      = "InvalidCertificateAnnotations"
+	reasonNamespaceResolveFailed  = "CertificateNamespaceResolveFailed"
+	reasonCertificateSharded      = "CertificateSharded"
 )
 
 type certificateHandler interface {
-	CreateCertificate(ctx context.Context, gateway *networkingv1beta1.Gateway, server *v1beta1.Server) error
-	UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *networkingv1beta1.Gateway, server *v1beta1.Server) error
+	CreateCertificate(ctx context.Context, namespace string, gateway *networkingv1.Gateway, server *apinetworkingv1.Server) error
+	UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *networkingv1.Gateway, server *apinetworkingv1.Server) error
 }
 
 type GatewayController struct {
-	istioClient          istioversionedclient.Interface
-	certClient           certmanagerclient.Interface
-	dryRun               bool
-	name                 string
-	certificateNamespace string
-	clusterIssuer        string
-	gatewayLookupCache   *cache.GatewayLookupCache
-	certHandler          certificateHandler
-	httpSolverLabel      string
+	config
+	istioClient istioversionedclient.Interface
+	certClient  certmanagerclient.Interface
+	name        string
+	certHandler certificateHandler
 }
 
 func NewGatewayController(istioClient istioversionedclient.Interface, certClient certmanagerclient.Interface, opts ...OptionsFunc) *GatewayController {
 	gr := &GatewayController{
-		name:                 "istio-gateway-controller",
-		istioClient:          istioClient,
-		certClient:           certClient,
-		certificateNamespace: "default",
-		clusterIssuer:        "default",
-	}
-
-	for _, opt := range opts {
-		opt(gr)
+		config:      newConfig(opts...),
+		name:        "istio-gateway-controller",
+		istioClient: istioClient,
+		certClient:  certClient,
 	}
 
 	gr.certHandler = gr
@@ -81,7 +92,7 @@ func (c *GatewayController) SetupWithManager(ctx context.Context, mgr manager.Ma
 
 	istioInformerFactory := istioinformers.NewSharedInformerFactoryWithOptions(c.istioClient, time.Second*30)
 
-	informer := istioInformerFactory.Networking().V1beta1().Gateways().Informer()
+	informer := istioInformerFactory.Networking().V1().Gateways().Informer()
 	if c.gatewayLookupCache != nil {
 		_, err := informer.AddEventHandler(k8scache.ResourceEventHandlerFuncs{
 			AddFunc:    c.gatewayLookupCache.AddFunc,
@@ -101,17 +112,80 @@ func (c *GatewayController) SetupWithManager(ctx context.Context, mgr manager.Ma
 		return err
 	}
 
+	if err := watchManagedCertificates(ctx, ctrl, c.certClient, false); err != nil {
+		return err
+	}
+
+	gatewayLister := istioInformerFactory.Networking().V1().Gateways().Lister()
+	if err := c.watchNamespaces(ctrl, func(namespace string) ([]string, error) {
+		gateways, err := gatewayLister.Gateways(namespace).List(labels.Everything())
+		names := []string{}
+		for _, g := range gateways {
+			names = append(names, g.Name)
+		}
+		return names, err
+	}); err != nil {
+		return err
+	}
+
 	istioInformerFactory.Start(ctx.Done())
 
 	return nil
 }
 
+// watchManagedCertificates requeues the owning Gateway when a managed Certificate is changed or deleted, so
+// manual edits are reverted and deleted Certificates are recreated without waiting for a Gateway resync.
+func watchManagedCertificates(ctx context.Context, ctrl controller.Controller, certClient certmanagerclient.Interface, gatewayAPI bool) error {
+	certmanagerInformerFactory := certmanagerinformers.NewSharedInformerFactoryWithOptions(certClient, time.Second*30, certmanagerinformers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+		listOptions.LabelSelector = v1beta1labels.ManagedLabelSelector()
+	}))
+
+	if err := ctrl.Watch(&source.Informer{
+		Informer: certmanagerInformerFactory.Certmanager().V1().Certificates().Informer(),
+		Handler:  handler.EnqueueRequestsFromMapFunc(gatewayForCertificate(gatewayAPI)),
+		// status updates from cert-manager do not change the generation and are ignored
+		Predicates: []predicate.Predicate{predicate.Or[client.Object](
+			predicate.GenerationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		)},
+	}); err != nil {
+		return err
+	}
+
+	certmanagerInformerFactory.Start(ctx.Done())
+
+	return nil
+}
+
+// gatewayForCertificate maps a managed Certificate to the Gateway named in its ManagedLabel, or every Gateway
+// referencing a shared Certificate, for either Gateway API or Istio Gateways.
+func gatewayForCertificate(gatewayAPI bool) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		if (obj.GetAnnotations()[v1beta1labels.ManagedGroupAnnotation] == gatewayapiv1beta1.GroupName) != gatewayAPI {
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, ref := range v1beta1labels.ManagingGateways(obj.GetLabels(), obj.GetAnnotations()) {
+			name, namespace := v1beta1labels.ParseManagedLabel(ref)
+			if name == "" || namespace == "" {
+				continue
+			}
+
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}})
+		}
+
+		return requests
+	}
+}
+
 func (c *GatewayController) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	// set up a convenient log object so we don't have to type request over and over again
 	log := log.FromContext(ctx)
 	log.Info("Reconciling Gateway...", "reconcile", request.String())
 	log.V(1).Info("Debug")
-	gateway, err := c.istioClient.NetworkingV1beta1().Gateways(request.Namespace).Get(ctx, request.Name, metav1.GetOptions{})
+	gateway, err := c.istioClient.NetworkingV1().Gateways(request.Namespace).Get(ctx, request.Name, metav1.GetOptions{})
 
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -129,23 +203,45 @@ func (c *GatewayController) Reconcile(ctx context.Context, request reconcile.Req
 		return reconcile.Result{}, nil
 	}
 
-	for _, s := range gateway.Spec.Servers {
+	namespace := ""
+	// servers sharing a credentialName, e.g. with the gateway granularity, share a Certificate, unmanaged servers
+	// keep the Secret provided by the user and are never merged
+	servers, unmanaged := splitUnmanagedServers(gateway)
+	for _, s := range append(serversByCredentialName(servers), unmanaged...) {
 		log.V(1).Info("Inspecting server", "hosts", s.Hosts)
 
-		// skip servers without a TLS config
-		if s.Tls == nil {
+		// skip servers without a managed TLS config, the Certificate of a server that left a managed TLS mode is
+		// unowned and garbage collected
+		if s.Tls == nil || !naming.IsManagedTLSMode(s.Tls.Mode) {
+			continue
+		}
+
+		if namespace == "" {
+			namespace, err = c.certificateNamespaceFor(gateway)
+			if err != nil {
+				log.Error(err, "Error resolving certificate namespace, requeued", "gateway", request.String())
+				c.recordEvent(gateway, corev1.EventTypeWarning, reasonNamespaceResolveFailed, "Failed to resolve the gateway workload namespace for Certificates, requeued: %s", err)
+				return reconcile.Result{
+					Requeue: true,
+				}, err
+			}
+		}
+
+		if naming.IsUnmanagedServer(gateway, s.Port.GetName(), s.Hosts) {
+			c.checkUnmanagedSecret(ctx, gateway, namespace, s.Tls.CredentialName, s.Port.GetName(), s.Hosts)
 			continue
 		}
 
-		cert, err := c.certClient.CertmanagerV1().Certificates(c.certificateNamespace).Get(ctx, s.Tls.CredentialName, metav1.GetOptions{})
+		cert, err := c.certClient.CertmanagerV1().Certificates(namespace).Get(ctx, s.Tls.CredentialName, metav1.GetOptions{})
 		if err != nil {
 			if errors.IsNotFound(err) {
-				if err := c.certHandler.CreateCertificate(ctx, gateway, s); err != nil {
+				if err := c.certHandler.CreateCertificate(ctx, namespace, gateway, s); err != nil {
 					return reconcile.Result{
 						Requeue: true,
 					}, err
 				}
 			} else {
+				c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateGetFailed, "Failed to get Certificate %s/%s, requeued: %s", namespace, s.Tls.CredentialName, err)
 				return reconcile.Result{
 					Requeue: true,
 				}, err
@@ -163,137 +259,226 @@ func (c *GatewayController) Reconcile(ctx context.Context, request reconcile.Req
 	return reconcile.Result{}, nil
 }
 
-func (c *GatewayController) CreateCertificate(ctx context.Context, gateway *networkingv1beta1.Gateway, server *v1beta1.Server) error {
-	log := log.FromContext(ctx)
-	issuer := c.clusterIssuer
-
-	if i, ok := gateway.Annotations[v1beta1labels.IssuerAnnotation]; ok {
-		issuer = i
+// certificateNamespaceFor returns the namespace of the gateway workload selected by the Gateway, or the fixed
+// certificate namespace without a namespace resolver.
+func (c *GatewayController) certificateNamespaceFor(gateway *networkingv1.Gateway) (string, error) {
+	if c.namespaceResolver == nil {
+		return c.certificateNamespace, nil
 	}
 
-	if server.Tls.Mode != v1beta1.ServerTLSSettings_SIMPLE {
+	return c.namespaceResolver.Namespace(gateway.Spec.Selector)
+}
+
+func (c *GatewayController) CreateCertificate(ctx context.Context, namespace string, gateway *networkingv1.Gateway, server *apinetworkingv1.Server) error {
+	if !naming.IsManagedTLSMode(server.Tls.Mode) {
 		return nil
 	}
 
+	return c.applyServerCertificates(ctx, namespace, gateway, server, nil)
+}
+
+// newCertificate builds the desired managed Certificate for the hosts of a Gateway, applying the issuer,
+// temporary certificate, http01 solver and Certificate spec overrides from the Gateway annotations. The
+// current Certificate, if any, supplies the values kept for invalid overrides.
+func (c *config) newCertificate(ctx context.Context, name string, gateway client.Object, hosts certificateHosts, current *v1certmanager.Certificate) *v1certmanager.Certificate {
+	annotations := gateway.GetAnnotations()
+
+	issuer, rejected := c.issuerFor(gateway)
+	if rejected {
+		c.recordIssuerNotAllowed(gateway, issuer)
+	}
+
 	cert := &v1certmanager.Certificate{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Certificate",
 			APIVersion: "cert-manager.io/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:        server.Tls.CredentialName,
-			Labels:      map[string]string{v1beta1labels.ManagedLabel: fmt.Sprintf("%s.%s", gateway.Name, gateway.Namespace)},
-			Annotations: map[string]string{},
+			Name:        name,
+			Labels:      map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(gateway.GetName(), gateway.GetNamespace())},
+			Annotations: v1beta1labels.ManagedAnnotations(gateway.GetName(), gateway.GetNamespace()),
 		},
 		Spec: v1certmanager.CertificateSpec{
-			DNSNames:   getSortedHostsWithoutNamespace(server.Hosts),
-			SecretName: server.Tls.CredentialName,
-			IssuerRef: v1.ObjectReference{
-				Kind:  "ClusterIssuer",
-				Name:  issuer,
-				Group: "cert-manager.io",
+			DNSNames:    hosts.dnsNames,
+			IPAddresses: hosts.ipAddresses,
+			SecretName:  name,
+			SecretTemplate: &v1certmanager.CertificateSecretTemplate{
+				// labels the Secret as managed so garbage collection can find it once the Certificate is gone
+				Labels: map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(gateway.GetName(), gateway.GetNamespace())},
 			},
+			IssuerRef: issuer,
 		},
 	}
 
-	if b, ok := gateway.Annotations[v1beta1labels.IssueTemporaryCertificateAnnotation]; ok && b == "true" {
+	if b, ok := annotations[v1beta1labels.IssueTemporaryCertificateAnnotation]; ok && b == "true" {
 		cert.Annotations[v1certmanager.IssueTemporaryCertificateAnnotation] = "true"
 	}
 
-	if b, ok := gateway.Annotations[v1beta1labels.HTTPSolverAnnotation]; ok && b == "true" {
+	if b, ok := annotations[v1beta1labels.HTTPSolverAnnotation]; ok && b == "true" {
 		cert.Labels[c.httpSolverLabel] = "true"
 	}
-	createOptions := metav1.CreateOptions{FieldManager: FieldManager}
-	if c.dryRun {
-		log.Info("[dryrun] create certificate", "cert", cert)
-		createOptions.DryRun = []string{metav1.DryRunAll}
-	}
-	_, err := c.certClient.CertmanagerV1().Certificates(c.certificateNamespace).Create(ctx, cert, createOptions)
-	return err
+
+	return c.updateCertificateOverrides(ctx, cert, current, gateway)
 }
 
-func getSortedHostsWithoutNamespace(serverHosts []string) []string {
-	hosts := make([]string, len(serverHosts))
+func (c *GatewayController) UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *networkingv1.Gateway, server *apinetworkingv1.Server) error {
+	return c.applyServerCertificates(ctx, cert.Namespace, gateway, server, cert)
+}
 
-	for i, h := range serverHosts {
-		parts := strings.Split(h, "/")
-		hosts[i] = h
-		if len(parts) > 1 {
-			hosts[i] = parts[1]
-		}
+// applyServerCertificates applies the Certificate named after the server credentialName, current is nil when
+// it does not exist yet.  Hosts beyond the maximum SANs per Certificate are split into shard Certificates
+// annotated with the credentialName and their index, the garbage collection controller deletes shards that
+// are no longer needed.  Istio serves a single credential per server, so the split is reported on the Gateway.
+// A shared Certificate is applied by a single referencing Gateway, the others only add their reference.
+func (c *GatewayController) applyServerCertificates(ctx context.Context, namespace string, gateway *networkingv1.Gateway, server *apinetworkingv1.Server, current *v1certmanager.Certificate) error {
+	shared := isSharedServer(server)
+	if shared && !isPrimaryReference(current, gateway) {
+		return c.addReference(ctx, gateway, current)
 	}
-	sort.Strings(hosts)
-	return hosts
-}
 
-func (c *GatewayController) UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *networkingv1beta1.Gateway, server *v1beta1.Server) error {
-	log := log.FromContext(ctx)
+	hosts := c.certificateHosts(ctx, c.certClient, gateway, namespace, server.Hosts)
+	if hosts.empty() {
+		return nil
+	}
 
-	cert, updatedIssuer := updateCertificateIssuer(ctx, cert, gateway)
-	cert, updatedDNSNames := updateCertificateDNSNames(ctx, cert, server)
-	cert, updatedHTTPSolver := updateHTTPSolver(ctx, cert, gateway, c.httpSolverLabel)
+	name := server.Tls.CredentialName
+	shards := shardHosts(hosts, c.maxSANs)
 
-	if updatedDNSNames || updatedIssuer || updatedHTTPSolver {
-		log.V(1).Info("pre-update", "cert", cert)
+	cert := c.newCertificate(ctx, name, gateway, shards[0], current)
+	if shared {
+		cert.Annotations[v1beta1labels.ReferencedByAnnotation] = referencedBy(current, gateway)
+	} else if current == nil {
+		c.reuseRenamedSecret(ctx, c.certClient, gateway, namespace, cert, serverCredentialNames(gateway.Spec.Servers))
+	}
 
-		updateOptions := metav1.UpdateOptions{FieldManager: FieldManager}
-		if c.dryRun {
-			log.Info("[dryrun] update certificate", "cert", cert)
-			updateOptions.DryRun = []string{metav1.DryRunAll}
-		}
+	if len(shards) > 1 {
+		cert.Annotations[v1beta1labels.ShardCountAnnotation] = strconv.Itoa(len(shards))
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateSharded, "Certificate %s/%s has %d names, more than the maximum of %d, and was split into %d Certificates. Istio only serves %s for the server, move the remaining hosts to another server", namespace, name, hosts.len(), c.maxSANs, len(shards), name)
+	}
 
-		_, err := c.certClient.CertmanagerV1().Certificates(c.certificateNamespace).Update(ctx, cert, updateOptions)
-		if err != nil {
-			log.Error(err, "error on certificate update", "cert", cert)
+	applied, err := c.applyCertificate(ctx, c.certClient, gateway, namespace, cert, current)
+	if err != nil || applied == nil {
+		return err
+	}
+
+	for i, shard := range shards[1:] {
+		if err := c.applyShardCertificate(ctx, gateway, namespace, cert, i+1, shard); err != nil {
+			return err
 		}
+	}
+
+	return c.reconcileClientCA(ctx, gateway, server, applied)
+}
+
+// applyShardCertificate applies the Certificate holding shard index of the hosts of the parent Certificate, a
+// shard of a shared Certificate is referenced by the same Gateways.
+func (c *GatewayController) applyShardCertificate(ctx context.Context, gateway *networkingv1.Gateway, namespace string, parent *v1certmanager.Certificate, index int, hosts certificateHosts) error {
+	name := parent.Name
+	shard := shardName(name, index)
+
+	current, err := c.certClient.CertmanagerV1().Certificates(namespace).Get(ctx, shard, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		current = nil
+	} else if err != nil {
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateGetFailed, "Failed to get Certificate %s/%s, requeued: %s", namespace, shard, err)
 		return err
 	}
 
-	return nil
+	cert := c.newCertificate(ctx, shard, gateway, hosts, current)
+	cert.Annotations[v1beta1labels.ShardOfAnnotation] = name
+	cert.Annotations[v1beta1labels.ShardIndexAnnotation] = strconv.Itoa(index)
+	if refs, ok := parent.Annotations[v1beta1labels.ReferencedByAnnotation]; ok {
+		cert.Annotations[v1beta1labels.ReferencedByAnnotation] = refs
+	}
+
+	_, err = c.applyCertificate(ctx, c.certClient, gateway, namespace, cert, current)
+	return err
 }
-func updateHTTPSolver(ctx context.Context, cert *v1certmanager.Certificate, gateway *networkingv1beta1.Gateway, label string) (*v1certmanager.Certificate, bool) {
+
+// applyCertificate server-side applies the desired Certificate under FieldManager, creating it when current
+// is nil, and returns the applied Certificate, or nil when the current Certificate is unmanaged and the adoption
+// policy refuses to take it over. Only the fields set on the desired Certificate are owned by the controller, so fields written by
+// other managers are left alone and fields the controller stops setting, e.g. a removed http01 solver label,
+// are removed.
+func (c *config) applyCertificate(ctx context.Context, certClient certmanagerclient.Interface, gateway client.Object, namespace string, cert, current *v1certmanager.Certificate) (*v1certmanager.Certificate, error) {
 	log := log.FromContext(ctx)
+	certificates := certClient.CertmanagerV1().Certificates(namespace)
+
+	force := true
+	patchOptions := metav1.PatchOptions{FieldManager: FieldManager, Force: &force}
+	if c.dryRun {
+		log.Info("[dryrun] apply certificate", "cert", cert)
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	if current != nil {
+		if !c.adoptsCertificate(ctx, gateway, cert, current) {
+			return nil, nil
+		}
 
-	if h, ok := gateway.Annotations[v1beta1labels.HTTPSolverAnnotation]; ok && h == "true" {
-		if cert.Labels == nil {
-			cert.Labels = map[string]string{}
+		if err := upgradeManagedFields(ctx, certificates, current, patchOptions.DryRun); err != nil {
+			log.Error(err, "error upgrading certificate managed fields", "cert", current.Name)
+			c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateUpdateFailed, "Failed to update Certificate %s/%s: %s", namespace, cert.Name, err)
+			return nil, err
 		}
+	}
+
+	data, err := certificateApplyPatch(cert)
+	if err != nil {
+		return nil, err
+	}
 
-		if l, ok := cert.Labels[label]; !ok || (ok && l != "true") {
-			log.V(1).Info("Adding http solver label to cert")
-			cert.Labels[label] = "true"
-			return cert, true
+	applied, err := certificates.Patch(ctx, cert.Name, types.ApplyPatchType, data, patchOptions)
+	if err != nil {
+		log.Error(err, "error on certificate apply", "cert", cert)
+		if current == nil {
+			c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateCreateFailed, "Failed to create Certificate %s/%s: %s", namespace, cert.Name, err)
 		} else {
-			return cert, false
+			c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateUpdateFailed, "Failed to update Certificate %s/%s: %s", namespace, cert.Name, err)
 		}
+		return nil, err
 	}
 
-	if l, ok := cert.Labels[label]; ok && l == "true" {
-		log.V(1).Info("Removing http solver label from cert")
-		delete(cert.Labels, label)
-		return cert, true
+	if current == nil {
+		c.recordEvent(gateway, corev1.EventTypeNormal, reasonCertificateCreated, "Created Certificate %s/%s", namespace, cert.Name)
+	} else if certificateChanged(current, applied) {
+		c.recordEvent(gateway, corev1.EventTypeNormal, reasonCertificateUpdated, "Updated Certificate %s/%s", namespace, cert.Name)
 	}
 
-	return cert, false
+	return applied, nil
+}
 
+// certificateApplyPatch encodes the desired Certificate for server-side apply. cert-manager does not
+// generate apply configurations, so the Certificate is encoded without its status, which the controller
+// never sets.
+func certificateApplyPatch(cert *v1certmanager.Certificate) ([]byte, error) {
+	return json.Marshal(struct {
+		metav1.TypeMeta `json:",inline"`
+		ObjectMeta      metav1.ObjectMeta             `json:"metadata"`
+		Spec            v1certmanager.CertificateSpec `json:"spec"`
+	}{
+		TypeMeta:   cert.TypeMeta,
+		ObjectMeta: cert.ObjectMeta,
+		Spec:       cert.Spec,
+	})
 }
-func updateCertificateIssuer(ctx context.Context, cert *v1certmanager.Certificate, gateway *networkingv1beta1.Gateway) (*v1certmanager.Certificate, bool) {
-	log := log.FromContext(ctx)
-	issuer := cert.Spec.IssuerRef.Name
 
-	if i, ok := gateway.Annotations[v1beta1labels.IssuerAnnotation]; ok {
-		log.V(1).Info("got issuer from annotation", "issuer", i)
-		issuer = i
+// upgradeManagedFields transfers ownership of the fields written by earlier releases, which used Create and
+// Update under the same FieldManager, to the apply manager. Without it those fields would stay owned by the
+// update manager and would not be removed when the controller stops applying them.
+func upgradeManagedFields(ctx context.Context, certificates certmanagerv1client.CertificateInterface, current *v1certmanager.Certificate, dryRun []string) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(current, sets.New(FieldManager), FieldManager)
+	if err != nil || patch == nil {
+		return err
 	}
 
-	updated := cert.Spec.IssuerRef.Name != issuer
-	cert.Spec.IssuerRef.Name = issuer
-	return cert, updated
+	_, err = certificates.Patch(ctx, current.Name, types.JSONPatchType, patch, metav1.PatchOptions{DryRun: dryRun})
+	return err
 }
 
-func updateCertificateDNSNames(ctx context.Context, cert *v1certmanager.Certificate, server *v1beta1.Server) (*v1certmanager.Certificate, bool) {
-	hosts := getSortedHostsWithoutNamespace(server.Hosts)
-	updated := !reflect.DeepEqual(hosts, cert.Spec.DNSNames)
-	cert.Spec.DNSNames = hosts
-	return cert, updated
+// certificateChanged reports whether applying changed the labels, annotations or spec of a Certificate.
+func certificateChanged(current, applied *v1certmanager.Certificate) bool {
+	return !equality.Semantic.DeepEqual(current.Labels, applied.Labels) ||
+		!equality.Semantic.DeepEqual(current.Annotations, applied.Annotations) ||
+		!equality.Semantic.DeepEqual(current.Spec, applied.Spec)
 }
@@ -2,10 +2,12 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -14,11 +16,12 @@ import (
 	certmanagerv1fake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/typed/certmanager/v1/fake"
 	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/cache"
 	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
-	networkingv1beta1 "istio.io/api/networking/v1beta1"
-	"istio.io/client-go/pkg/apis/networking/v1beta1"
+	networkingv1 "istio.io/api/networking/v1"
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
 	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
-	networkingv1beta1fake "istio.io/client-go/pkg/clientset/versioned/typed/networking/v1beta1/fake"
+	networkingv1fake "istio.io/client-go/pkg/clientset/versioned/typed/networking/v1/fake"
 	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
@@ -46,7 +49,7 @@ type GatewayOptions struct {
 	GatewayLookupCache *cache.GatewayLookupCache
 	Annotations        map[string]string
 	Labels             map[string]string
-	Servers            []*networkingv1beta1.Server
+	Servers            []*networkingv1.Server
 }
 
 func namespacedHost(host string) string {
@@ -91,7 +94,7 @@ func WithGLC(glc *cache.GatewayLookupCache) func(*GatewayOptions) {
 	}
 }
 
-func AppendServer(server *networkingv1beta1.Server) func(*GatewayOptions) {
+func AppendServer(server *networkingv1.Server) func(*GatewayOptions) {
 	return func(gopt *GatewayOptions) {
 		gopt.Servers = append(gopt.Servers, server)
 	}
@@ -133,22 +136,22 @@ func gatewayListAction(opts ...func(*GatewayOptions)) func(k8stesting.Action) (b
 	gopts := NewGatewayOptions(opts...)
 	return func(action k8stesting.Action) (bool, runtime.Object, error) {
 
-		servers := []*networkingv1beta1.Server{
+		servers := []*networkingv1.Server{
 			{
 				Hosts: gopts.Hosts,
-				Tls: &networkingv1beta1.ServerTLSSettings{
+				Tls: &networkingv1.ServerTLSSettings{
 					CredentialName: gopts.CredentialName,
-					Mode:           networkingv1beta1.ServerTLSSettings_SIMPLE,
+					Mode:           networkingv1.ServerTLSSettings_SIMPLE,
 				},
 			},
 		}
 
 		servers = append(servers, gopts.Servers...)
 
-		return true, &v1beta1.Gateway{
+		return true, &istiov1.Gateway{
 			TypeMeta: metav1.TypeMeta{
 				Kind:       "Gateway",
-				APIVersion: "networking.istio.io/v1beta1",
+				APIVersion: "networking.istio.io/v1",
 			},
 			ObjectMeta: metav1.ObjectMeta{
 				Name:        TestGatewayName,
@@ -156,7 +159,7 @@ func gatewayListAction(opts ...func(*GatewayOptions)) func(k8stesting.Action) (b
 				Annotations: gopts.Annotations,
 				Labels:      gopts.Labels,
 			},
-			Spec: networkingv1beta1.Gateway{
+			Spec: networkingv1.Gateway{
 				Servers: servers,
 			},
 		}, nil
@@ -169,9 +172,35 @@ type TestHelper struct {
 	Controller  *controllerSpy
 }
 
+// newTestCertClient returns a fake cert-manager clientset that handles server-side apply of Certificates.
+// The fake tracker merges apply patches into existing objects only, so the reactor instead replaces the
+// tracked Certificate with the applied one, as the API server does for fields owned by a single manager.
+func newTestCertClient(certs ...runtime.Object) *certmanagerfake.Clientset {
+	ccs := certmanagerfake.NewSimpleClientset(certs...)
+	ccs.PrependReactor("patch", "certificates", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		cert := &v1certmanager.Certificate{}
+		if err := json.Unmarshal(patch.GetPatch(), cert); err != nil {
+			return true, nil, err
+		}
+		cert.Namespace = patch.GetNamespace()
+
+		err := ccs.Tracker().Update(patch.GetResource(), cert, cert.Namespace)
+		if k8serrors.IsNotFound(err) {
+			err = ccs.Tracker().Create(patch.GetResource(), cert, cert.Namespace)
+		}
+		return true, cert, err
+	})
+	return ccs
+}
+
 func NewTestHelper(opts *GatewayOptions) *TestHelper {
 	ics := istiofake.NewSimpleClientset()
-	ccs := certmanagerfake.NewSimpleClientset(opts.Certificates...)
+	ccs := newTestCertClient(opts.Certificates...)
 	return &TestHelper{
 		IstioClient: ics,
 		CertClient:  ccs,
@@ -183,7 +212,7 @@ func NewTestHelperWithGateways(opts ...func(*GatewayOptions)) *TestHelper {
 	gopts := NewGatewayOptions(opts...)
 	helper := NewTestHelper(gopts)
 
-	helper.IstioClient.NetworkingV1beta1().(*networkingv1beta1fake.FakeNetworkingV1beta1).PrependReactor(
+	helper.IstioClient.NetworkingV1().(*networkingv1fake.FakeNetworkingV1).PrependReactor(
 		"get",
 		"gateways",
 		gatewayListAction(opts...),
@@ -201,6 +230,7 @@ func NewTestHelperWithCertificates(opts ...func(*GatewayOptions)) *TestHelper {
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      TestCertificateName,
 			Namespace: TestCertNamespace,
+			Labels:    map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace)},
 		},
 		Spec: v1certmanager.CertificateSpec{
 			DNSNames: []string{"test1.example.com", "test2.example.com"},
@@ -224,15 +254,15 @@ func setupControllerWithSpy(cs *istiofake.Clientset, certFake *certmanagerfake.C
 	return spy
 }
 
-func (r *controllerSpy) CreateCertificate(ctx context.Context, gateway *v1beta1.Gateway, server *networkingv1beta1.Server) error {
+func (r *controllerSpy) CreateCertificate(ctx context.Context, namespace string, gateway *istiov1.Gateway, server *networkingv1.Server) error {
 	r.CreateCalled++
 	if r.Error {
 		return fmt.Errorf("mock create error")
 	}
-	return r.GatewayController.CreateCertificate(ctx, gateway, server)
+	return r.GatewayController.CreateCertificate(ctx, namespace, gateway, server)
 }
 
-func (r *controllerSpy) UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *v1beta1.Gateway, server *networkingv1beta1.Server) error {
+func (r *controllerSpy) UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *istiov1.Gateway, server *networkingv1.Server) error {
 	r.UpdateCalled++
 	if r.Error {
 		return fmt.Errorf("mock update error")
@@ -288,6 +318,139 @@ func TestGatewyReconcile_CreatesCertificateForGateway(t *testing.T) {
 	assert.Len(t, certList.Items, 1)
 }
 
+func TestGatewayReconcile_RecordsCertificateEvents(t *testing.T) {
+	t.Parallel()
+	recorder := record.NewFakeRecorder(10)
+	helper := NewTestHelperWithGateways()
+	helper.Controller.recorder = recorder
+	assertCreateCertificateCalled(t, helper)
+	assert.Len(t, recorder.Events, 1)
+	assert.Equal(t, fmt.Sprintf("Normal CertificateCreated Created Certificate %s/%s", TestCertNamespace, TestCertificateName), <-recorder.Events)
+
+	dryRunRecorder := record.NewFakeRecorder(10)
+	helper = NewTestHelperWithCertificates(WithTestDryRun(), AppendHosts("an.example.com"))
+	helper.Controller.recorder = dryRunRecorder
+	assertCertificateUpdated(t, helper)
+	assert.Len(t, dryRunRecorder.Events, 0)
+}
+
+func TestGatewayReconcile_SkipsCertificateOfPassthroughServer(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithGateways(
+		AppendServer(&networkingv1.Server{
+			Hosts: []string{"passthrough.example.com"},
+			Tls: &networkingv1.ServerTLSSettings{
+				CredentialName: "passthrough-cert",
+				Mode:           networkingv1.ServerTLSSettings_PASSTHROUGH,
+			},
+		}),
+		AppendCertificates(&v1certmanager.Certificate{ObjectMeta: metav1.ObjectMeta{Name: "passthrough-cert", Namespace: TestCertNamespace}}))
+
+	assertCreateCertificateCalled(t, helper)
+	assert.Equal(t, 0, helper.Controller.UpdateCalled)
+}
+
+func TestGatewayReconcile_RecreatesDeletedCertificate(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithGateways()
+	assertCreateCertificateCalled(t, helper)
+
+	err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Delete(context.TODO(), TestCertificateName, metav1.DeleteOptions{})
+	assert.NoError(t, err)
+
+	_, err = helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, helper.Controller.CreateCalled)
+
+	_, err = helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+}
+
+type testNamespaceResolver struct {
+	namespace string
+	err       error
+}
+
+func (r testNamespaceResolver) Namespace(selector map[string]string) (string, error) {
+	return r.namespace, r.err
+}
+
+func TestGatewayReconcile_CreateCertificateInWorkloadNamespace(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithGateways()
+	helper.Controller.namespaceResolver = testNamespaceResolver{namespace: "istio-ingress"}
+	assertCreateCertificateCalled(t, helper)
+
+	cert, err := helper.CertClient.CertmanagerV1().Certificates("istio-ingress").Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "istio-ingress", cert.Namespace)
+
+	certList, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, certList.Items, 0)
+}
+
+func TestGatewayReconcile_RequeuesWhenWorkloadNamespaceUnresolved(t *testing.T) {
+	t.Parallel()
+	recorder := record.NewFakeRecorder(10)
+	helper := NewTestHelperWithGateways()
+	helper.Controller.recorder = recorder
+	helper.Controller.namespaceResolver = testNamespaceResolver{err: fmt.Errorf("no gateway Pods or Services match selector")}
+
+	r, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.Error(t, err)
+	assert.Equal(t, reconcile.Result{Requeue: true}, r)
+	assert.Equal(t, 0, helper.Controller.CreateCalled)
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning CertificateNamespaceResolveFailed")
+}
+
+func TestGatewayForCertificate(t *testing.T) {
+	t.Parallel()
+
+	managed := func(annotations map[string]string) *v1certmanager.Certificate {
+		return &v1certmanager.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        TestCertificateName,
+				Namespace:   TestCertNamespace,
+				Labels:      map[string]string{v1beta1labels.ManagedLabel: fmt.Sprintf("%s.%s", TestGatewayName, TestNamespace)},
+				Annotations: annotations,
+			},
+		}
+	}
+	gatewayAPIAnnotations := map[string]string{v1beta1labels.ManagedGroupAnnotation: "gateway.networking.k8s.io"}
+
+	tests := []struct {
+		description string
+		cert        *v1certmanager.Certificate
+		gatewayAPI  bool
+		want        []reconcile.Request
+	}{
+		{description: "istio certificate", cert: managed(nil), want: []reconcile.Request{reconcileRequest()}},
+		{description: "istio certificate for gateway api controller", cert: managed(nil), gatewayAPI: true},
+		{description: "gateway api certificate", cert: managed(gatewayAPIAnnotations), gatewayAPI: true, want: []reconcile.Request{reconcileRequest()}},
+		{description: "gateway api certificate for istio controller", cert: managed(gatewayAPIAnnotations)},
+		{description: "unmanaged certificate", cert: &v1certmanager.Certificate{}},
+		{
+			description: "shared certificate",
+			cert:        managed(map[string]string{v1beta1labels.ReferencedByAnnotation: fmt.Sprintf("%s.%s,other.%s", TestGatewayName, TestNamespace, TestNamespace)}),
+			want:        []reconcile.Request{reconcileRequest(), {NamespacedName: types.NamespacedName{Name: "other", Namespace: TestNamespace}}},
+		},
+		{
+			description: "gateway name with dots",
+			cert: &v1certmanager.Certificate{ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue("api.example.com-gw", TestNamespace)},
+				Annotations: v1beta1labels.ManagedAnnotations("api.example.com-gw", TestNamespace),
+			}},
+			want: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "api.example.com-gw", Namespace: TestNamespace}}},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, gatewayForCertificate(test.gatewayAPI)(context.TODO(), test.cert), test.description)
+	}
+}
+
 func TestGatewayReconcile_CallsCreateCertificateWithError(t *testing.T) {
 	t.Parallel()
 	helper := NewTestHelperWithGateways()
@@ -313,7 +476,30 @@ func TestGatewayReconcile_CreateCertificateLabeledAsManaged(t *testing.T) {
 	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
 	assert.NoError(t, err)
 	assert.NotNil(t, cert)
-	assert.Equal(t, fmt.Sprintf("%s.%s", TestGatewayName, TestNamespace), cert.Labels[v1beta1labels.ManagedLabel])
+	assert.Equal(t, v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace), cert.Labels[v1beta1labels.ManagedLabel])
+	assert.Equal(t, TestGatewayName, cert.Annotations[v1beta1labels.ManagedNameAnnotation])
+	assert.Equal(t, TestNamespace, cert.Annotations[v1beta1labels.ManagedNamespaceAnnotation])
+	assert.Equal(t, v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace), cert.Spec.SecretTemplate.Labels[v1beta1labels.ManagedLabel])
+}
+
+func TestGatewayReconcile_MigratesLegacyManagedLabel(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithGateways()
+	legacy := &v1certmanager.Certificate{ObjectMeta: metav1.ObjectMeta{
+		Name:      TestCertificateName,
+		Namespace: TestCertNamespace,
+		Labels:    map[string]string{v1beta1labels.ManagedLabel: fmt.Sprintf("%s.%s", TestGatewayName, TestNamespace)},
+	}}
+	_, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Create(context.TODO(), legacy, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	_, err = helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace), cert.Labels[v1beta1labels.ManagedLabel])
+	assert.Equal(t, v1beta1labels.ManagedAnnotations(TestGatewayName, TestNamespace)[v1beta1labels.ManagedNamespaceAnnotation], cert.Annotations[v1beta1labels.ManagedNamespaceAnnotation])
 }
 
 func TestGatewayReconcile_CreateCertificateWithHosts(t *testing.T) {
@@ -348,6 +534,21 @@ func TestGatewayReconcile_CreatCertificateWithClusterIssuerFromGatewayAnnotation
 	assert.Equal(t, "ClusterIssuer", cert.Spec.IssuerRef.Kind)
 }
 
+func TestGatewayReconcile_CreateCertificateWithIssuerKindAndGroupFromGatewayAnnotations(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithGateways(WithAnnotations(map[string]string{
+		v1beta1labels.IssuerAnnotation:      "step",
+		v1beta1labels.IssuerKindAnnotation:  "StepIssuer",
+		v1beta1labels.IssuerGroupAnnotation: "certmanager.step.sm",
+	}))
+	assertCreateCertificateCalled(t, helper)
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "step", cert.Spec.IssuerRef.Name)
+	assert.Equal(t, "StepIssuer", cert.Spec.IssuerRef.Kind)
+	assert.Equal(t, "certmanager.step.sm", cert.Spec.IssuerRef.Group)
+}
+
 func TestGatewayReconcile_CreateCertificateWithTempCertAnnotation(t *testing.T) {
 	t.Parallel()
 	helper := NewTestHelperWithGateways(WithAnnotations(map[string]string{
@@ -363,10 +564,10 @@ func TestGatewayReconcile_CreateCertificateWithTempCertAnnotation(t *testing.T)
 
 func TestGatewayReconcile_SkipCertificateForTLSModePassthrough(t *testing.T) {
 	t.Parallel()
-	helper := NewTestHelperWithGateways(AppendServer(&networkingv1beta1.Server{
+	helper := NewTestHelperWithGateways(AppendServer(&networkingv1.Server{
 		Hosts: []string{"pass.example.com"},
-		Tls: &networkingv1beta1.ServerTLSSettings{
-			Mode: networkingv1beta1.ServerTLSSettings_AUTO_PASSTHROUGH,
+		Tls: &networkingv1.ServerTLSSettings{
+			Mode: networkingv1.ServerTLSSettings_AUTO_PASSTHROUGH,
 		},
 	}))
 	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
@@ -413,6 +614,58 @@ func TestGatewayReconcile_UpdatesCertificateWithNewIssuer(t *testing.T) {
 	assert.Equal(t, "ClusterIssuer", cert.Spec.IssuerRef.Kind)
 }
 
+func TestGatewayReconcile_UpdatesCertificateWithNewIssuerKind(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithCertificates(WithAnnotations(map[string]string{
+		v1beta1labels.IssuerAnnotation:     "team-ca",
+		v1beta1labels.IssuerKindAnnotation: "Issuer",
+	}))
+	assertCertificateUpdated(t, helper)
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "team-ca", cert.Spec.IssuerRef.Name)
+	assert.Equal(t, "Issuer", cert.Spec.IssuerRef.Kind)
+	assert.Equal(t, "cert-manager.io", cert.Spec.IssuerRef.Group)
+}
+
+func TestNewCertificateIssuer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		annotations map[string]string
+		want        v1.ObjectReference
+	}{
+		{
+			description: "no annotations uses the default issuer",
+			want:        v1.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "issuer annotation changes the name",
+			annotations: map[string]string{v1beta1labels.IssuerAnnotation: "team-ca"},
+			want:        v1.ObjectReference{Name: "team-ca", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "kind annotation changes the kind",
+			annotations: map[string]string{v1beta1labels.IssuerKindAnnotation: "Issuer"},
+			want:        v1.ObjectReference{Name: "default", Kind: "Issuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "group annotation changes the group",
+			annotations: map[string]string{v1beta1labels.IssuerGroupAnnotation: "certmanager.step.sm"},
+			want:        v1.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "certmanager.step.sm"},
+		},
+	}
+
+	c := newConfig(WithDefaultClusterIssuer("default"))
+	for _, test := range tests {
+		gateway := &istiov1.Gateway{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations}}
+
+		cert := c.newCertificate(context.TODO(), TestCertificateName, gateway, certificateHosts{dnsNames: []string{"test1.example.com"}}, nil)
+		assert.Equal(t, test.want, cert.Spec.IssuerRef, test.description)
+	}
+}
+
 func TestGatewayReconcile_UpdatesCertificateWithSolver(t *testing.T) {
 	helper := NewTestHelperWithCertificates(WithAnnotations(map[string]string{v1beta1labels.HTTPSolverAnnotation: "true"}))
 	assertCertificateUpdated(t, helper)
@@ -435,7 +688,8 @@ func TestGatewayReconcile_DeleteCertificateSolver(t *testing.T) {
 				Name:      TestCertificateName,
 				Namespace: TestCertNamespace,
 				Labels: map[string]string{
-					"use-istio-http01-solver": "true",
+					v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace),
+					"use-istio-http01-solver":  "true",
 				},
 			},
 			Spec: v1certmanager.CertificateSpec{
@@ -460,6 +714,9 @@ func TestGatewayReconcile_DeleteCertificateSolver(t *testing.T) {
 func TestGatewayReconcile_UpdateCertificateNoOp(t *testing.T) {
 	t.Parallel()
 	helper := NewTestHelperWithCertificates()
+	recorder := record.NewFakeRecorder(10)
+	helper.Controller.recorder = recorder
+
 	updated := 0
 	helper.CertClient.CertmanagerV1().(*certmanagerv1fake.FakeCertmanagerV1).PrependReactor("update", "certificates", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
 		updated++
@@ -468,47 +725,89 @@ func TestGatewayReconcile_UpdateCertificateNoOp(t *testing.T) {
 
 	assertCertificateUpdated(t, helper)
 	assert.Equal(t, 0, updated)
+	assert.Len(t, recorder.Events, 1)
+	<-recorder.Events
+
+	// applying the same Certificate again is not reported as an update
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, updated)
+	assert.Len(t, recorder.Events, 0)
 }
 
-func TestGatewayReconcile_SkipGatewayWithoutLabel(t *testing.T) {
+func TestGatewayReconcile_AppliesCertificate(t *testing.T) {
 	t.Parallel()
-	helper := NewTestHelperWithGateways(WithLabels(map[string]string{}))
+	helper := NewTestHelperWithCertificates()
+	assertCertificateUpdated(t, helper)
 
-	assert.Equal(t, 0, helper.Controller.CreateCalled)
-	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
-	assert.Error(t, err)
-	assert.Nil(t, cert)
+	patches := []k8stesting.PatchAction{}
+	for _, action := range helper.CertClient.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patches = append(patches, patch)
+		}
+	}
+
+	assert.Len(t, patches, 1)
+	assert.Equal(t, types.ApplyPatchType, patches[0].GetPatchType())
+
+	applied := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(patches[0].GetPatch(), &applied))
+	assert.Equal(t, "cert-manager.io/v1", applied["apiVersion"])
+	assert.Equal(t, "Certificate", applied["kind"])
+	assert.NotContains(t, applied, "status")
 }
 
-func TestHTTPSolverLabelIdempotency(t *testing.T) {
+func TestGatewayReconcile_UpgradesManagedFields(t *testing.T) {
 	t.Parallel()
-	cert := &v1certmanager.Certificate{
-		ObjectMeta: metav1.ObjectMeta{
-			Labels: map[string]string{},
+	helper := NewTestHelperWithGateways(AppendCertificates(&v1certmanager.Certificate{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Certificate",
+			APIVersion: "cert-manager.io/v1",
 		},
-	}
-	gw := &v1beta1.Gateway{
 		ObjectMeta: metav1.ObjectMeta{
-			Annotations: map[string]string{v1beta1labels.HTTPSolverAnnotation: "true"},
+			Name:            TestCertificateName,
+			Namespace:       TestCertNamespace,
+			ResourceVersion: "1",
+			Labels:          map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace)},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:    FieldManager,
+					Operation:  metav1.ManagedFieldsOperationUpdate,
+					APIVersion: "cert-manager.io/v1",
+					FieldsType: "FieldsV1",
+					FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:dnsNames":{}}}`)},
+				},
+			},
 		},
+	}))
+	assertCertificateUpdated(t, helper)
+
+	patchTypes := []types.PatchType{}
+	for _, action := range helper.CertClient.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patchTypes = append(patchTypes, patch.GetPatchType())
+		}
 	}
-	// Add the label
-	cert, updated := updateHTTPSolver(context.Background(), cert, gw, "use-istio-http01-solver")
-	assert.True(t, updated)
-	assert.Equal(t, "true", cert.Labels["use-istio-http01-solver"])
 
-	// Test for idempotency
-	cert, updated = updateHTTPSolver(context.Background(), cert, gw, "use-istio-http01-solver")
-	assert.False(t, updated)
-	assert.Equal(t, "true", cert.Labels["use-istio-http01-solver"])
+	assert.Equal(t, []types.PatchType{types.JSONPatchType, types.ApplyPatchType}, patchTypes)
+}
+
+func TestGatewayReconcile_SkipGatewayWithoutLabel(t *testing.T) {
+	t.Parallel()
+	helper := NewTestHelperWithGateways(WithLabels(map[string]string{}))
+
+	assert.Equal(t, 0, helper.Controller.CreateCalled)
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.Error(t, err)
+	assert.Nil(t, cert)
 }
 
 func TestGatewayReconcile_MixedServers(t *testing.T) {
 	t.Parallel()
 	helper := NewTestHelperWithGateways(
-		AppendServer(&networkingv1beta1.Server{
+		AppendServer(&networkingv1.Server{
 			Hosts: []string{"http.example.com"},
-			Port: &networkingv1beta1.Port{
+			Port: &networkingv1.Port{
 				Number:   80,
 				Protocol: "HTTP",
 				Name:     "http",
@@ -0,0 +1,241 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	certmanagerclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapiclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+	gatewayapiinformers "sigs.k8s.io/gateway-api/pkg/client/informers/externalversions"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+type listenerCertificateHandler interface {
+	CreateCertificate(ctx context.Context, gateway *gatewayapiv1beta1.Gateway, name string, listeners []*gatewayapiv1beta1.Listener) error
+	UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *gatewayapiv1beta1.Gateway, listeners []*gatewayapiv1beta1.Listener) error
+}
+
+// GatewayAPIController manages Certificates for Kubernetes Gateway API (gateway.networking.k8s.io) Gateways.
+// Certificates are created in the namespace of the Gateway, since listener certificateRefs cannot reference
+// Secrets in other namespaces without a ReferenceGrant.
+type GatewayAPIController struct {
+	config
+	gatewayClient gatewayapiclient.Interface
+	certClient    certmanagerclient.Interface
+	name          string
+	certHandler   listenerCertificateHandler
+}
+
+func NewGatewayAPIController(gatewayClient gatewayapiclient.Interface, certClient certmanagerclient.Interface, opts ...OptionsFunc) *GatewayAPIController {
+	gr := &GatewayAPIController{
+		config:        newConfig(opts...),
+		name:          "gateway-api-controller",
+		gatewayClient: gatewayClient,
+		certClient:    certClient,
+	}
+
+	gr.certHandler = gr
+	return gr
+}
+
+func (c *GatewayAPIController) SetupWithManager(ctx context.Context, mgr manager.Manager) error {
+	ctrl, err := controller.New(c.name, mgr, controller.Options{
+		Reconciler: c,
+	})
+	if err != nil {
+		return err
+	}
+
+	gatewayInformerFactory := gatewayapiinformers.NewSharedInformerFactoryWithOptions(c.gatewayClient, time.Second*30)
+
+	if err := ctrl.Watch(&source.Informer{
+		Informer: gatewayInformerFactory.Gateway().V1beta1().Gateways().Informer(),
+		Handler:  &handler.EnqueueRequestForObject{},
+	}); err != nil {
+		return err
+	}
+
+	if err := watchManagedCertificates(ctx, ctrl, c.certClient, true); err != nil {
+		return err
+	}
+
+	gatewayLister := gatewayInformerFactory.Gateway().V1beta1().Gateways().Lister()
+	if err := c.watchNamespaces(ctrl, func(namespace string) ([]string, error) {
+		gateways, err := gatewayLister.Gateways(namespace).List(labels.Everything())
+		names := []string{}
+		for _, g := range gateways {
+			names = append(names, g.Name)
+		}
+		return names, err
+	}); err != nil {
+		return err
+	}
+
+	gatewayInformerFactory.Start(ctx.Done())
+
+	return nil
+}
+
+func (c *GatewayAPIController) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	log.Info("Reconciling Gateway API Gateway...", "reconcile", request.String())
+
+	gateway, err := c.gatewayClient.GatewayV1beta1().Gateways(request.Namespace).Get(ctx, request.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil // garbage collection will handle
+		}
+
+		log.Error(err, "Error reconciling gateway, requeued")
+		return reconcile.Result{
+			Requeue: true,
+		}, err
+	}
+
+	//If we don't have the tls management label or it isn't set to true return
+	if val, ok := gateway.Labels[v1beta1labels.InjectSimpleCredentialNameLabel]; !ok || val != "true" {
+		return reconcile.Result{}, nil
+	}
+
+	// listeners referencing the same Secret, e.g. with the gateway granularity, share a Certificate
+	names, listeners := listenersByCertificateName(managedListeners(gateway))
+	for _, name := range names {
+		log.V(1).Info("Inspecting listeners", "certificate", name, "listeners", listenerNames(listeners[name]))
+
+		cert, err := c.certClient.CertmanagerV1().Certificates(gateway.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				if err := c.certHandler.CreateCertificate(ctx, gateway, name, listeners[name]); err != nil {
+					return reconcile.Result{
+						Requeue: true,
+					}, err
+				}
+			} else {
+				c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateGetFailed, "Failed to get Certificate %s/%s, requeued: %s", gateway.Namespace, name, err)
+				return reconcile.Result{
+					Requeue: true,
+				}, err
+			}
+		} else {
+			log.V(1).Info("Found certificate", "certificate", name)
+			if err := c.certHandler.UpdateCertificate(ctx, cert.DeepCopy(), gateway, listeners[name]); err != nil {
+				return reconcile.Result{
+					Requeue: true,
+				}, err
+			}
+		}
+	}
+
+	// unmanaged listeners keep the Secret provided by the user
+	for _, l := range unmanagedListeners(gateway) {
+		name, _ := listenerCertificateName(l)
+		c.checkUnmanagedSecret(ctx, gateway, gateway.Namespace, name, string(l.Name), listenerHostnames([]*gatewayapiv1beta1.Listener{l}))
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (c *GatewayAPIController) CreateCertificate(ctx context.Context, gateway *gatewayapiv1beta1.Gateway, name string, listeners []*gatewayapiv1beta1.Listener) error {
+	log := log.FromContext(ctx)
+
+	hostnames := listenerHostnames(listeners)
+	if len(hostnames) == 0 {
+		log.Info("skipping listeners without a hostname", "gateway", gateway.Name, "listeners", listenerNames(listeners))
+		return nil
+	}
+
+	hosts := c.certificateHosts(ctx, c.certClient, gateway, gateway.Namespace, hostnames)
+	if hosts.empty() {
+		return nil
+	}
+
+	cert := c.newCertificate(ctx, name, gateway, hosts, nil)
+	cert.Annotations[v1beta1labels.ManagedGroupAnnotation] = gatewayapiv1beta1.GroupName
+	c.reuseRenamedSecret(ctx, c.certClient, gateway, gateway.Namespace, cert, listenerCertificateNames(gateway.Spec.Listeners))
+
+	_, err := c.applyCertificate(ctx, c.certClient, gateway, gateway.Namespace, cert, nil)
+	return err
+}
+
+func (c *GatewayAPIController) UpdateCertificate(ctx context.Context, cert *v1certmanager.Certificate, gateway *gatewayapiv1beta1.Gateway, listeners []*gatewayapiv1beta1.Listener) error {
+	log := log.FromContext(ctx)
+
+	hostnames := listenerHostnames(listeners)
+	if len(hostnames) == 0 {
+		log.Info("skipping listeners without a hostname", "gateway", gateway.Name, "listeners", listenerNames(listeners))
+		return nil
+	}
+
+	hosts := c.certificateHosts(ctx, c.certClient, gateway, gateway.Namespace, hostnames)
+	if hosts.empty() {
+		return nil
+	}
+
+	desired := c.newCertificate(ctx, cert.Name, gateway, hosts, cert)
+	desired.Annotations[v1beta1labels.ManagedGroupAnnotation] = gatewayapiv1beta1.GroupName
+
+	_, err := c.applyCertificate(ctx, c.certClient, gateway, gateway.Namespace, desired, cert)
+	return err
+}
+
+// listenerCertificateName returns the name of the Secret, and therefore the Certificate, referenced by a
+// terminating TLS listener. Only the first certificateRef is managed and it must be a Secret in the
+// namespace of the Gateway.
+func listenerCertificateName(listener *gatewayapiv1beta1.Listener) (string, bool) {
+	if listener.TLS == nil || len(listener.TLS.CertificateRefs) == 0 {
+		return "", false
+	}
+
+	if listener.TLS.Mode != nil && *listener.TLS.Mode != gatewayapiv1beta1.TLSModeTerminate {
+		return "", false
+	}
+
+	ref := listener.TLS.CertificateRefs[0]
+	if ref.Group != nil && *ref.Group != "" {
+		return "", false
+	}
+
+	if ref.Kind != nil && *ref.Kind != "Secret" {
+		return "", false
+	}
+
+	if ref.Namespace != nil {
+		return "", false
+	}
+
+	return string(ref.Name), ref.Name != ""
+}
+
+// listenerHostnames returns the hostnames of the listeners, listeners without a hostname are skipped.
+func listenerHostnames(listeners []*gatewayapiv1beta1.Listener) []string {
+	hostnames := []string{}
+	for _, l := range listeners {
+		if l.Hostname != nil && *l.Hostname != "" {
+			hostnames = append(hostnames, string(*l.Hostname))
+		}
+	}
+
+	return hostnames
+}
+
+func listenerNames(listeners []*gatewayapiv1beta1.Listener) []string {
+	names := make([]string, len(listeners))
+	for i, l := range listeners {
+		names[i] = string(l.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapifake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+func newTestListener(name, hostname, certificateRef string, mode gatewayapiv1beta1.TLSModeType) gatewayapiv1beta1.Listener {
+	h := gatewayapiv1beta1.Hostname(hostname)
+	return gatewayapiv1beta1.Listener{
+		Name:     gatewayapiv1beta1.SectionName(name),
+		Hostname: &h,
+		Port:     443,
+		Protocol: gatewayapiv1beta1.HTTPSProtocolType,
+		TLS: &gatewayapiv1beta1.GatewayTLSConfig{
+			Mode: &mode,
+			CertificateRefs: []gatewayapiv1beta1.SecretObjectReference{
+				{Name: gatewayapiv1beta1.ObjectName(certificateRef)},
+			},
+		},
+	}
+}
+
+func newTestGatewayAPIGateway(labels, annotations map[string]string, listeners ...gatewayapiv1beta1.Listener) *gatewayapiv1beta1.Gateway {
+	return &gatewayapiv1beta1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        TestGatewayName,
+			Namespace:   TestNamespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: gatewayapiv1beta1.GatewaySpec{
+			GatewayClassName: "istio",
+			Listeners:        listeners,
+		},
+	}
+}
+
+func newTestGatewayAPIController(t *testing.T, gateway *gatewayapiv1beta1.Gateway, certs ...runtime.Object) *GatewayAPIController {
+	gwc := gatewayapifake.NewSimpleClientset()
+	// the v1alpha2 types are aliases of v1beta1, create through the typed client so the object is tracked as v1beta1
+	if gateway != nil {
+		_, err := gwc.GatewayV1beta1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	return NewGatewayAPIController(gwc, newTestCertClient(certs...),
+		WithCertificateNamespace(TestCertNamespace),
+		WithHTTPSolverLabel("use-istio-http01-solver"))
+}
+
+func TestGatewayAPIReconcile_OkOnNotExists(t *testing.T) {
+	t.Parallel()
+	c := newTestGatewayAPIController(t, nil)
+	r, err := c.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, r)
+}
+
+func TestGatewayAPIReconcile_CreatesCertificateForListener(t *testing.T) {
+	t.Parallel()
+
+	gateway := newTestGatewayAPIGateway(
+		map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		map[string]string{v1beta1labels.IssuerAnnotation: "testissuer"},
+		newTestListener("https", "a.example.com", TestCertificateName, gatewayapiv1beta1.TLSModeTerminate),
+		newTestListener("passthrough", "b.example.com", "passthrough-cert", gatewayapiv1beta1.TLSModePassthrough),
+	)
+
+	c := newTestGatewayAPIController(t, gateway)
+	r, err := c.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, r)
+
+	// certificates are created in the namespace of the gateway
+	certList, err := c.certClient.CertmanagerV1().Certificates(TestNamespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, certList.Items, 1)
+
+	cert := certList.Items[0]
+	assert.Equal(t, TestCertificateName, cert.Name)
+	assert.Equal(t, TestCertificateName, cert.Spec.SecretName)
+	assert.Equal(t, []string{"a.example.com"}, cert.Spec.DNSNames)
+	assert.Equal(t, "testissuer", cert.Spec.IssuerRef.Name)
+	assert.Equal(t, v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace), cert.Labels[v1beta1labels.ManagedLabel])
+	assert.Equal(t, TestGatewayName, cert.Annotations[v1beta1labels.ManagedNameAnnotation])
+	assert.Equal(t, gatewayapiv1beta1.GroupName, cert.Annotations[v1beta1labels.ManagedGroupAnnotation])
+}
+
+func TestGatewayAPIReconcile_SkipGatewayWithoutLabel(t *testing.T) {
+	t.Parallel()
+
+	gateway := newTestGatewayAPIGateway(nil, nil,
+		newTestListener("https", "a.example.com", TestCertificateName, gatewayapiv1beta1.TLSModeTerminate))
+
+	c := newTestGatewayAPIController(t, gateway)
+	_, err := c.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	certList, err := c.certClient.CertmanagerV1().Certificates(TestNamespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, certList.Items, 0)
+}
+
+func TestGatewayAPIReconcile_UpdatesCertificateWithNewHostname(t *testing.T) {
+	t.Parallel()
+
+	gateway := newTestGatewayAPIGateway(
+		map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		map[string]string{v1beta1labels.HTTPSolverAnnotation: "true"},
+		newTestListener("https", "new.example.com", TestCertificateName, gatewayapiv1beta1.TLSModeTerminate))
+
+	existing := &v1certmanager.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TestCertificateName,
+			Namespace: TestNamespace,
+			Labels:    map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace)},
+		},
+		Spec: v1certmanager.CertificateSpec{
+			DNSNames: []string{"old.example.com"},
+			IssuerRef: v1.ObjectReference{
+				Kind:  "ClusterIssuer",
+				Name:  "default",
+				Group: "cert-manager.io",
+			},
+		},
+	}
+
+	c := newTestGatewayAPIController(t, gateway, existing)
+	_, err := c.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	cert, err := c.certClient.CertmanagerV1().Certificates(TestNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"new.example.com"}, cert.Spec.DNSNames)
+	assert.Equal(t, "true", cert.Labels["use-istio-http01-solver"])
+}
+
+func TestListenerCertificateName(t *testing.T) {
+	t.Parallel()
+
+	group := gatewayapiv1beta1.Group("example.com")
+	kind := gatewayapiv1beta1.Kind("ConfigMap")
+	namespace := gatewayapiv1beta1.Namespace("other")
+
+	withRef := func(ref gatewayapiv1beta1.SecretObjectReference) gatewayapiv1beta1.Listener {
+		l := newTestListener("https", "a.example.com", "", gatewayapiv1beta1.TLSModeTerminate)
+		l.TLS.CertificateRefs = []gatewayapiv1beta1.SecretObjectReference{ref}
+		return l
+	}
+
+	noTLS := newTestListener("https", "a.example.com", "cert", gatewayapiv1beta1.TLSModeTerminate)
+	noTLS.TLS = nil
+
+	defaultMode := newTestListener("https", "a.example.com", "cert", gatewayapiv1beta1.TLSModeTerminate)
+	defaultMode.TLS.Mode = nil
+
+	tests := []struct {
+		description string
+		listener    gatewayapiv1beta1.Listener
+		want        string
+		wantOK      bool
+	}{
+		{description: "terminate listener", listener: newTestListener("https", "a.example.com", "cert", gatewayapiv1beta1.TLSModeTerminate), want: "cert", wantOK: true},
+		{description: "mode defaults to terminate", listener: defaultMode, want: "cert", wantOK: true},
+		{description: "passthrough listener", listener: newTestListener("tls", "a.example.com", "cert", gatewayapiv1beta1.TLSModePassthrough)},
+		{description: "listener without tls", listener: noTLS},
+		{description: "non core group", listener: withRef(gatewayapiv1beta1.SecretObjectReference{Group: &group, Name: "cert"})},
+		{description: "non secret kind", listener: withRef(gatewayapiv1beta1.SecretObjectReference{Kind: &kind, Name: "cert"})},
+		{description: "cross namespace reference", listener: withRef(gatewayapiv1beta1.SecretObjectReference{Namespace: &namespace, Name: "cert"})},
+	}
+
+	for _, test := range tests {
+		name, ok := listenerCertificateName(&test.listener)
+		assert.Equal(t, test.want, name, test.description)
+		assert.Equal(t, test.wantOK, ok, test.description)
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// isSharedServer reports whether the server is a SIMPLE server named after a shared credentialName.  The name
+// given at admission is kept, not recomputed, so a change of the issuer policy of the namespace does not unshare
+// the Certificate of Gateways admitted before it.
+func isSharedServer(server *apinetworkingv1.Server) bool {
+	return server.Tls != nil && server.Tls.Mode == apinetworkingv1.ServerTLSSettings_SIMPLE && naming.IsSharedCredentialName(server.Tls.CredentialName)
+}
+
+// serversByCredentialName merges the managed TLS servers sharing a credentialName into a single server holding
+// the hosts of all of them, in the order the credentialNames first appear.  A mutual TLS server is preferred
+// as the merged server, so its client CA is managed.  Other servers are returned unchanged.
+func serversByCredentialName(servers []*apinetworkingv1.Server) []*apinetworkingv1.Server {
+	out := []*apinetworkingv1.Server{}
+	// index of the merged server of each credentialName in out
+	merged := map[string]int{}
+
+	for _, s := range servers {
+		if s.Tls == nil || !naming.IsManagedTLSMode(s.Tls.Mode) || s.Tls.CredentialName == "" {
+			out = append(out, s)
+			continue
+		}
+
+		i, ok := merged[s.Tls.CredentialName]
+		if !ok {
+			merged[s.Tls.CredentialName] = len(out)
+			out = append(out, s.DeepCopy())
+			continue
+		}
+
+		m := out[i]
+		hosts := append(m.Hosts, s.Hosts...)
+		if !naming.IsMutualTLSMode(m.Tls.Mode) && naming.IsMutualTLSMode(s.Tls.Mode) {
+			// proto messages must not be copied by value, the mutual TLS server replaces the merged one
+			m = s.DeepCopy()
+			out[i] = m
+		}
+		m.Hosts = sets.List(sets.New(hosts...))
+	}
+
+	return out
+}
+
+// listenersByCertificateName groups the terminating TLS listeners by the Certificate they reference, in the
+// order the Certificates first appear.
+func listenersByCertificateName(listeners []gatewayapiv1beta1.Listener) ([]string, map[string][]*gatewayapiv1beta1.Listener) {
+	names := []string{}
+	grouped := map[string][]*gatewayapiv1beta1.Listener{}
+
+	for i := range listeners {
+		l := &listeners[i]
+
+		name, ok := listenerCertificateName(l)
+		if !ok {
+			continue
+		}
+
+		if _, ok := grouped[name]; !ok {
+			names = append(names, name)
+		}
+		grouped[name] = append(grouped[name], l)
+	}
+
+	return names, grouped
+}
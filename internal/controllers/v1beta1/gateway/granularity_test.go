@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "istio.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+func TestServersByCredentialName(t *testing.T) {
+	t.Parallel()
+
+	servers := []*networkingv1.Server{
+		{Hosts: []string{"b.example.com"}, Tls: &networkingv1.ServerTLSSettings{Mode: networkingv1.ServerTLSSettings_SIMPLE, CredentialName: "shared"}},
+		{Hosts: []string{"other.example.com"}, Tls: &networkingv1.ServerTLSSettings{Mode: networkingv1.ServerTLSSettings_SIMPLE, CredentialName: "other"}},
+		{Hosts: []string{"a.example.com", "b.example.com"}, Tls: &networkingv1.ServerTLSSettings{Mode: networkingv1.ServerTLSSettings_MUTUAL, CredentialName: "shared"}},
+		{Hosts: []string{"passthrough.example.com"}, Tls: &networkingv1.ServerTLSSettings{Mode: networkingv1.ServerTLSSettings_PASSTHROUGH, CredentialName: "shared"}},
+		{Hosts: []string{"http.example.com"}},
+	}
+
+	merged := serversByCredentialName(servers)
+	assert.Len(t, merged, 4)
+	assert.Equal(t, "shared", merged[0].Tls.CredentialName)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, merged[0].Hosts)
+	assert.Equal(t, networkingv1.ServerTLSSettings_MUTUAL, merged[0].Tls.Mode)
+	assert.Equal(t, servers[1], merged[1])
+	assert.Equal(t, servers[3], merged[2])
+	assert.Equal(t, servers[4], merged[3])
+
+	// the Gateway spec is not modified
+	assert.Equal(t, []string{"b.example.com"}, servers[0].Hosts)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, servers[2].Hosts)
+	assert.NotSame(t, servers[2], merged[0])
+}
+
+func TestGatewayReconcile_SharedCredentialName(t *testing.T) {
+	t.Parallel()
+
+	helper := NewTestHelperWithGateways(AppendServer(&networkingv1.Server{
+		Hosts: []string{"shared.example.com"},
+		Port:  &networkingv1.Port{Number: 8443, Name: "https-alt"},
+		Tls: &networkingv1.ServerTLSSettings{
+			CredentialName: TestCertificateName,
+			Mode:           networkingv1.ServerTLSSettings_SIMPLE,
+		},
+	}))
+
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, helper.Controller.CreateCalled)
+
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shared.example.com", "test1.example.com", "test2.example.com"}, cert.Spec.DNSNames)
+}
+
+func TestGatewayAPIReconcile_SharedCertificateRef(t *testing.T) {
+	t.Parallel()
+
+	gateway := newTestGatewayAPIGateway(
+		map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		nil,
+		newTestListener("https-a", "a.example.com", TestCertificateName, gatewayapiv1beta1.TLSModeTerminate),
+		newTestListener("https-b", "b.example.com", TestCertificateName, gatewayapiv1beta1.TLSModeTerminate),
+	)
+
+	c := newTestGatewayAPIController(t, gateway)
+	_, err := c.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	cert, err := c.certClient.CertmanagerV1().Certificates(TestNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, cert.Spec.DNSNames)
+}
@@ -0,0 +1,148 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanagerclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+)
+
+const reasonHostsSkipped = "CertificateHostsSkipped"
+
+// certificateHosts are the Gateway hosts normalized into Certificate dnsNames and ipAddresses, along with
+// the hosts that cannot be issued and the reason they were skipped.
+type certificateHosts struct {
+	dnsNames    []string
+	ipAddresses []string
+	skipped     []string
+}
+
+func (h certificateHosts) empty() bool {
+	return h.len() == 0
+}
+
+func (h certificateHosts) len() int {
+	return len(h.dnsNames) + len(h.ipAddresses)
+}
+
+// shardHosts splits hosts into shards of at most max names, dnsNames first, in their sorted order so the
+// shards are deterministic.  A max of zero, or hosts within the limit, return a single shard.
+func shardHosts(hosts certificateHosts, max int) []certificateHosts {
+	if max <= 0 || hosts.len() <= max {
+		return []certificateHosts{hosts}
+	}
+
+	shards := make([]certificateHosts, (hosts.len()+max-1)/max)
+	for i, name := range hosts.dnsNames {
+		shards[i/max].dnsNames = append(shards[i/max].dnsNames, name)
+	}
+
+	for i, ip := range hosts.ipAddresses {
+		n := len(hosts.dnsNames) + i
+		shards[n/max].ipAddresses = append(shards[n/max].ipAddresses, ip)
+	}
+
+	return shards
+}
+
+// shardName returns the name of the Certificate, and Secret, holding a shard.  The first shard keeps the
+// credentialName so it is the Certificate Istio serves.  A credentialName too long for the suffix, e.g. one the
+// admission webhook already truncated, is truncated again with a hash of the credentialName.
+func shardName(name string, index int) string {
+	if index == 0 {
+		return name
+	}
+
+	suffix := fmt.Sprintf("-shard-%d", index)
+	if max := naming.SecretNameMaxLength - len(suffix); len(name) > max {
+		name = naming.TruncateWithHash(name, max, name)
+	}
+
+	return name + suffix
+}
+
+// normalizeHosts converts Istio server hosts or Gateway API listener hostnames into Certificate names, see
+// naming.NormalizeHosts.
+func normalizeHosts(hosts []string, wildcards bool) certificateHosts {
+	n := naming.NormalizeHosts(hosts, wildcards)
+	return certificateHosts{
+		dnsNames:    n.DNSNames,
+		ipAddresses: n.IPAddresses,
+		skipped:     n.Skipped,
+	}
+}
+
+// certificateHosts normalizes the hosts of a server or listener and reports any skipped hosts on the Gateway.
+// The issuer is only looked up when a host is a wildcard.
+func (c *config) certificateHosts(ctx context.Context, certClient certmanagerclient.Interface, gateway client.Object, namespace string, hosts []string) certificateHosts {
+	wildcards := false
+	for _, h := range hosts {
+		if strings.Contains(h, "*.") {
+			wildcards = c.issuerSupportsWildcards(ctx, certClient, gateway, namespace)
+			break
+		}
+	}
+
+	out := normalizeHosts(hosts, wildcards)
+	if len(out.skipped) > 0 {
+		log.FromContext(ctx).Info("skipping hosts that cannot be issued", "gateway", gateway.GetName(), "namespace", gateway.GetNamespace(), "hosts", out.skipped)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonHostsSkipped, "Skipped hosts that cannot be added to a Certificate: %s", strings.Join(out.skipped, ", "))
+	}
+
+	return out
+}
+
+// issuerSupportsWildcards reports whether the issuer selected by the Gateway can issue "*." names.  ACME
+// issuers can only issue wildcards with a DNS01 solver, so Gateways requesting the http01 solver and ACME
+// issuers without a DNS01 solver cannot.  Other issuer types, external issuers and issuers that cannot be read
+// are assumed to support them and cert-manager reports any failure on the Certificate.
+func (c *config) issuerSupportsWildcards(ctx context.Context, certClient certmanagerclient.Interface, gateway client.Object, namespace string) bool {
+	log := log.FromContext(ctx)
+
+	if b, ok := gateway.GetAnnotations()[v1beta1labels.HTTPSolverAnnotation]; ok && b == "true" {
+		return false
+	}
+
+	ref := c.issuerRef(gateway)
+	if ref.Group != defaultIssuerGroup {
+		return true
+	}
+
+	var issuer v1certmanager.GenericIssuer
+	var err error
+	switch ref.Kind {
+	case v1certmanager.ClusterIssuerKind:
+		issuer, err = certClient.CertmanagerV1().ClusterIssuers().Get(ctx, ref.Name, metav1.GetOptions{})
+	case v1certmanager.IssuerKind:
+		issuer, err = certClient.CertmanagerV1().Issuers(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	default:
+		return true
+	}
+
+	if err != nil {
+		log.V(1).Info("unable to read issuer, assuming wildcard support", "kind", ref.Kind, "name", ref.Name, "error", err.Error())
+		return true
+	}
+
+	acme := issuer.GetSpec().ACME
+	if acme == nil {
+		return true
+	}
+
+	for _, solver := range acme.Solvers {
+		if solver.DNS01 != nil {
+			return true
+		}
+	}
+
+	return false
+}
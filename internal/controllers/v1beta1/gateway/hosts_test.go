@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	"github.com/stretchr/testify/assert"
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	cmacme "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanagerfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+)
+
+func TestIssuerSupportsWildcards(t *testing.T) {
+	t.Parallel()
+
+	http01 := cmacme.ACMEChallengeSolver{HTTP01: &cmacme.ACMEChallengeSolverHTTP01{}}
+	dns01 := cmacme.ACMEChallengeSolver{DNS01: &cmacme.ACMEChallengeSolverDNS01{}}
+
+	issuers := []runtime.Object{
+		&v1certmanager.ClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "acme-http01"},
+			Spec:       v1certmanager.IssuerSpec{IssuerConfig: v1certmanager.IssuerConfig{ACME: &cmacme.ACMEIssuer{Solvers: []cmacme.ACMEChallengeSolver{http01}}}},
+		},
+		&v1certmanager.ClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "acme-dns01"},
+			Spec:       v1certmanager.IssuerSpec{IssuerConfig: v1certmanager.IssuerConfig{ACME: &cmacme.ACMEIssuer{Solvers: []cmacme.ACMEChallengeSolver{http01, dns01}}}},
+		},
+		&v1certmanager.ClusterIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "ca"},
+			Spec:       v1certmanager.IssuerSpec{IssuerConfig: v1certmanager.IssuerConfig{CA: &v1certmanager.CAIssuer{SecretName: "ca"}}},
+		},
+		&v1certmanager.Issuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "acme-http01", Namespace: TestCertNamespace},
+			Spec:       v1certmanager.IssuerSpec{IssuerConfig: v1certmanager.IssuerConfig{ACME: &cmacme.ACMEIssuer{Solvers: []cmacme.ACMEChallengeSolver{http01}}}},
+		},
+	}
+
+	tests := []struct {
+		description string
+		annotations map[string]string
+		want        bool
+	}{
+		{description: "ACME without a DNS01 solver", annotations: map[string]string{v1beta1labels.IssuerAnnotation: "acme-http01"}, want: false},
+		{description: "ACME with a DNS01 solver", annotations: map[string]string{v1beta1labels.IssuerAnnotation: "acme-dns01"}, want: true},
+		{description: "http01 solver requested", annotations: map[string]string{v1beta1labels.IssuerAnnotation: "acme-dns01", v1beta1labels.HTTPSolverAnnotation: "true"}, want: false},
+		{description: "CA issuer", annotations: map[string]string{v1beta1labels.IssuerAnnotation: "ca"}, want: true},
+		{description: "namespaced issuer", annotations: map[string]string{v1beta1labels.IssuerAnnotation: "acme-http01", v1beta1labels.IssuerKindAnnotation: "Issuer"}, want: false},
+		{description: "external issuer", annotations: map[string]string{v1beta1labels.IssuerAnnotation: "acme-http01", v1beta1labels.IssuerGroupAnnotation: "certmanager.step.sm"}, want: true},
+		{description: "missing issuer", annotations: map[string]string{v1beta1labels.IssuerAnnotation: "missing"}, want: true},
+	}
+
+	c := newConfig(WithDefaultClusterIssuer("ca"))
+	certClient := certmanagerfake.NewSimpleClientset(issuers...)
+	for _, test := range tests {
+		gateway := &istiov1.Gateway{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations}}
+		assert.Equal(t, test.want, c.issuerSupportsWildcards(context.TODO(), certClient, gateway, TestCertNamespace), test.description)
+	}
+}
+
+func TestGatewayReconcile_ReportsSkippedHosts(t *testing.T) {
+	t.Parallel()
+
+	helper := NewTestHelperWithGateways(WithHosts("*", "10.0.0.1", "Test1.example.com"))
+	recorder := record.NewFakeRecorder(10)
+	helper.Controller.recorder = recorder
+
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test1.example.com"}, cert.Spec.DNSNames)
+	assert.Equal(t, []string{"10.0.0.1"}, cert.Spec.IPAddresses)
+
+	assert.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, "Warning CertificateHostsSkipped")
+}
+
+func TestGatewayReconcile_SkipsServerWithoutIssuableHosts(t *testing.T) {
+	t.Parallel()
+
+	helper := NewTestHelperWithGateways(WithHosts("*"))
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	certs, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, certs.Items, 0)
+}
+
+func TestShardHosts(t *testing.T) {
+	t.Parallel()
+
+	hosts := certificateHosts{
+		dnsNames:    []string{"a.example.com", "b.example.com", "c.example.com"},
+		ipAddresses: []string{"10.0.0.1", "10.0.0.2"},
+	}
+
+	assert.Equal(t, []certificateHosts{hosts}, shardHosts(hosts, 0))
+	assert.Equal(t, []certificateHosts{hosts}, shardHosts(hosts, 5))
+	assert.Equal(t, []certificateHosts{
+		{dnsNames: []string{"a.example.com", "b.example.com"}},
+		{dnsNames: []string{"c.example.com"}, ipAddresses: []string{"10.0.0.1"}},
+		{ipAddresses: []string{"10.0.0.2"}},
+	}, shardHosts(hosts, 2))
+
+	assert.Equal(t, "mycert", shardName("mycert", 0))
+	assert.Equal(t, "mycert-shard-2", shardName("mycert", 2))
+
+	// a maximum length credentialName is truncated before the suffix
+	long := strings.Repeat("a", naming.SecretNameMaxLength)
+	assert.Equal(t, long, shardName(long, 0))
+	for _, index := range []int{1, 10} {
+		shard := shardName(long, index)
+		assert.Len(t, shard, naming.SecretNameMaxLength)
+		assert.Regexp(t, fmt.Sprintf("^a+-[0-9a-f]{8}-shard-%d$", index), shard)
+	}
+	assert.NotEqual(t, shardName(long, 1), shardName(strings.Repeat("a", naming.SecretNameMaxLength-1)+"b", 1))
+}
+
+func TestGatewayReconcile_ShardsMaximumLengthCredentialName(t *testing.T) {
+	t.Parallel()
+
+	name := strings.Repeat("a", naming.SecretNameMaxLength)
+	helper := NewTestHelperWithGateways(WithCredentialName(name), WithHosts("a.example.com", "b.example.com", "c.example.com"))
+	helper.Controller.maxSANs = 2
+
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	certificates := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace)
+
+	_, err = certificates.Get(context.TODO(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	shard, err := certificates.Get(context.TODO(), shardName(name, 1), metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(shard.Name), naming.SecretNameMaxLength)
+	assert.Equal(t, shard.Name, shard.Spec.SecretName)
+	assert.Equal(t, []string{"c.example.com"}, shard.Spec.DNSNames)
+	assert.Equal(t, name, shard.Annotations[v1beta1labels.ShardOfAnnotation])
+}
+
+func TestGatewayReconcile_ShardsCertificates(t *testing.T) {
+	t.Parallel()
+
+	helper := NewTestHelperWithGateways(WithHosts("a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"))
+	helper.Controller.maxSANs = 2
+	recorder := record.NewFakeRecorder(10)
+	helper.Controller.recorder = recorder
+
+	_, err := helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	certificates := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace)
+
+	cert, err := certificates.Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, cert.Spec.DNSNames)
+	assert.Equal(t, "3", cert.Annotations[v1beta1labels.ShardCountAnnotation])
+
+	for i, dnsNames := range [][]string{{"c.example.com", "d.example.com"}, {"e.example.com"}} {
+		shard, err := certificates.Get(context.TODO(), shardName(TestCertificateName, i+1), metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, dnsNames, shard.Spec.DNSNames)
+		assert.Equal(t, shard.Name, shard.Spec.SecretName)
+		assert.Equal(t, TestCertificateName, shard.Annotations[v1beta1labels.ShardOfAnnotation])
+		assert.Equal(t, strconv.Itoa(i+1), shard.Annotations[v1beta1labels.ShardIndexAnnotation])
+		assert.Equal(t, v1beta1labels.ManagedLabelValue(TestGatewayName, TestNamespace), shard.Labels[v1beta1labels.ManagedLabel])
+	}
+
+	assert.Contains(t, <-recorder.Events, "Warning CertificateSharded")
+
+	// no longer sharded once the hosts fit
+	helper.Controller.maxSANs = 5
+	_, err = helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	cert, err = certificates.Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, cert.Spec.DNSNames, 5)
+	assert.NotContains(t, cert.Annotations, v1beta1labels.ShardCountAnnotation)
+}
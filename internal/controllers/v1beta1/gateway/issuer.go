@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+const reasonIssuerNotAllowed = "IssuerNotAllowed"
+
+// issuerRef returns the issuer of the Certificates of a Gateway, see issuerFor.
+func (c *config) issuerRef(gateway metav1.Object) v1.ObjectReference {
+	ref, _ := c.issuerFor(gateway)
+	return ref
+}
+
+// issuerFor returns the issuer of the Certificates of a Gateway resolved from the Gateway and namespace
+// annotations, see naming.ResolveIssuer.  True is returned when the requested issuer is not allowed.
+func (c *config) issuerFor(gateway metav1.Object) (v1.ObjectReference, bool) {
+	return naming.ResolveIssuer(c.clusterIssuer, c.gatewayNamespace(gateway), gateway)
+}
+
+// gatewayNamespace returns the namespace of the Gateway from the namespace lister, or nil without a lister or
+// when the namespace cannot be read.
+func (c *config) gatewayNamespace(gateway metav1.Object) *corev1.Namespace {
+	if c.namespaceLister == nil {
+		return nil
+	}
+
+	ns, err := c.namespaceLister.Get(gateway.GetNamespace())
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Log.Error(err, "failed to Get namespace, using the default issuer", "namespace", gateway.GetNamespace())
+		}
+		return nil
+	}
+
+	return ns
+}
+
+// recordIssuerNotAllowed reports a Gateway whose requested issuer is not allowed in its namespace and was
+// replaced by the default issuer ref.
+func (c *config) recordIssuerNotAllowed(gateway client.Object, ref v1.ObjectReference) {
+	requested := naming.AnnotatedIssuer(ref, gateway.GetAnnotations())
+	c.recordEvent(gateway, corev1.EventTypeWarning, reasonIssuerNotAllowed, "%s %s is not allowed in namespace %s, using %s %s", requested.Kind, requested.Name, gateway.GetNamespace(), ref.Kind, ref.Name)
+}
+
+// watchNamespaces requeues the Gateways of a namespace when the namespace annotations change, so a new default
+// issuer or allowlist applies without waiting for a Gateway resync.  list returns the Gateway names of a namespace.
+func (c *config) watchNamespaces(ctrl controller.Controller, list func(namespace string) ([]string, error)) error {
+	if c.namespaceInformer == nil {
+		return nil
+	}
+
+	return ctrl.Watch(&source.Informer{
+		Informer: c.namespaceInformer,
+		Handler: handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			names, err := list(obj.GetName())
+			if err != nil {
+				log.FromContext(ctx).Error(err, "failed to List gateways of namespace", "namespace", obj.GetName())
+				return nil
+			}
+
+			requests := []reconcile.Request{}
+			for _, name := range names {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: obj.GetName()}})
+			}
+
+			return requests
+		}),
+		Predicates: []predicate.Predicate{predicate.AnnotationChangedPredicate{}},
+	})
+}
@@ -0,0 +1,145 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	k8scache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+// newTestNamespaceLister returns a NamespaceLister holding the test namespace with the annotations.
+func newTestNamespaceLister(t *testing.T, annotations map[string]string) corev1listers.NamespaceLister {
+	indexer := k8scache.NewIndexer(k8scache.MetaNamespaceKeyFunc, k8scache.Indexers{})
+	assert.NoError(t, indexer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: TestNamespace, Annotations: annotations}}))
+
+	return corev1listers.NewNamespaceLister(indexer)
+}
+
+func TestIssuerFor(t *testing.T) {
+	t.Parallel()
+
+	teamIssuer := map[string]string{
+		v1beta1labels.IssuerAnnotation:     "team-ca",
+		v1beta1labels.IssuerKindAnnotation: "Issuer",
+	}
+
+	tests := []struct {
+		description  string
+		namespace    map[string]string
+		annotations  map[string]string
+		want         v1.ObjectReference
+		wantRejected bool
+	}{
+		{
+			description: "no annotations uses the default issuer",
+			want:        v1.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "namespace annotations replace the default issuer",
+			namespace:   teamIssuer,
+			want:        v1.ObjectReference{Name: "team-ca", Kind: "Issuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "gateway annotations override the namespace issuer",
+			namespace:   teamIssuer,
+			annotations: map[string]string{v1beta1labels.IssuerAnnotation: "letsencrypt"},
+			want:        v1.ObjectReference{Name: "letsencrypt", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "allowed issuer by name",
+			namespace:   map[string]string{v1beta1labels.AllowedIssuersAnnotation: "letsencrypt, Issuer/team-ca"},
+			annotations: map[string]string{v1beta1labels.IssuerAnnotation: "letsencrypt"},
+			want:        v1.ObjectReference{Name: "letsencrypt", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "allowed issuer by kind and name",
+			namespace:   map[string]string{v1beta1labels.AllowedIssuersAnnotation: "letsencrypt, Issuer/team-ca"},
+			annotations: teamIssuer,
+			want:        v1.ObjectReference{Name: "team-ca", Kind: "Issuer", Group: "cert-manager.io"},
+		},
+		{
+			description:  "issuer of another kind is rejected",
+			namespace:    map[string]string{v1beta1labels.AllowedIssuersAnnotation: "Issuer/team-ca"},
+			annotations:  map[string]string{v1beta1labels.IssuerAnnotation: "team-ca"},
+			want:         v1.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+			wantRejected: true,
+		},
+		{
+			description: "issuer of another group is rejected",
+			namespace:   map[string]string{v1beta1labels.AllowedIssuersAnnotation: "letsencrypt"},
+			annotations: map[string]string{
+				v1beta1labels.IssuerAnnotation:      "letsencrypt",
+				v1beta1labels.IssuerGroupAnnotation: "certmanager.step.sm",
+			},
+			want:         v1.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+			wantRejected: true,
+		},
+		{
+			description: "namespace default is always allowed",
+			namespace: map[string]string{
+				v1beta1labels.IssuerAnnotation:         "team-ca",
+				v1beta1labels.AllowedIssuersAnnotation: "letsencrypt",
+			},
+			want: v1.ObjectReference{Name: "team-ca", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+		},
+		{
+			description: "rejected issuer falls back to the namespace default",
+			namespace: map[string]string{
+				v1beta1labels.IssuerAnnotation:         "team-ca",
+				v1beta1labels.AllowedIssuersAnnotation: "letsencrypt",
+			},
+			annotations:  map[string]string{v1beta1labels.IssuerAnnotation: "selfsigned"},
+			want:         v1.ObjectReference{Name: "team-ca", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+			wantRejected: true,
+		},
+	}
+
+	for _, test := range tests {
+		c := newConfig(WithDefaultClusterIssuer("default"))
+		c.namespaceLister = newTestNamespaceLister(t, test.namespace)
+		gateway := &istiov1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: TestNamespace, Annotations: test.annotations}}
+
+		got, rejected := c.issuerFor(gateway)
+		assert.Equal(t, test.want, got, test.description)
+		assert.Equal(t, test.wantRejected, rejected, test.description)
+	}
+
+	// the default issuer is used without a lister or for an unknown namespace
+	c := newConfig(WithDefaultClusterIssuer("default"))
+	gateway := &istiov1.Gateway{ObjectMeta: metav1.ObjectMeta{Namespace: "other"}}
+	got, rejected := c.issuerFor(gateway)
+	assert.Equal(t, "default", got.Name)
+	assert.False(t, rejected)
+
+	c.namespaceLister = newTestNamespaceLister(t, teamIssuer)
+	got, _ = c.issuerFor(gateway)
+	assert.Equal(t, "default", got.Name)
+}
+
+func TestGatewayReconcile_RecordsIssuerNotAllowed(t *testing.T) {
+	t.Parallel()
+
+	helper := NewTestHelperWithGateways(WithAnnotations(map[string]string{v1beta1labels.IssuerAnnotation: "selfsigned"}))
+	helper.Controller.namespaceLister = newTestNamespaceLister(t, map[string]string{
+		v1beta1labels.IssuerAnnotation:         "team-ca",
+		v1beta1labels.AllowedIssuersAnnotation: "letsencrypt",
+	})
+	recorder := record.NewFakeRecorder(10)
+	helper.Controller.recorder = recorder
+
+	assertCreateCertificateCalled(t, helper)
+
+	cert, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "team-ca", cert.Spec.IssuerRef.Name)
+	assert.Contains(t, <-recorder.Events, "Warning IssuerNotAllowed ClusterIssuer selfsigned is not allowed in namespace test, using ClusterIssuer team-ca")
+}
@@ -1,13 +1,59 @@
 package gateway
 
 import (
+	"sync"
+
 	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/cache"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	k8scache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
-type OptionsFunc func(*GatewayController)
+// config holds the settings shared by the Istio and Gateway API controllers.
+type config struct {
+	dryRun               bool
+	certificateNamespace string
+	clusterIssuer        string
+	gatewayLookupCache   *cache.GatewayLookupCache
+	httpSolverLabel      string
+	recorder             record.EventRecorder
+	namespaceResolver    NamespaceResolver
+	coreClient           kubernetes.Interface
+	maxSANs              int
+	adoptionPolicy       AdoptionPolicy
+	namespaceLister      corev1listers.NamespaceLister
+	namespaceInformer    k8scache.SharedIndexInformer
+	// last reported state of each unmanaged Secret, shared by the copies of the config
+	unmanagedSecretStates *sync.Map
+}
+
+type OptionsFunc func(*config)
+
+// NamespaceResolver resolves an Istio Gateway selector to the namespace of the gateway workload.
+type NamespaceResolver interface {
+	Namespace(selector map[string]string) (string, error)
+}
+
+func newConfig(opts ...OptionsFunc) config {
+	cfg := config{
+		certificateNamespace:  "default",
+		clusterIssuer:         "default",
+		adoptionPolicy:        AdoptionPolicyRefuse,
+		unmanagedSecretStates: &sync.Map{},
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
 
 func WithCertificateNamespace(namespace string) OptionsFunc {
-	return func(gc *GatewayController) {
+	return func(gc *config) {
 		if namespace != "" {
 			gc.certificateNamespace = namespace
 		}
@@ -15,25 +61,85 @@ func WithCertificateNamespace(namespace string) OptionsFunc {
 }
 
 func WithDefaultClusterIssuer(issuer string) OptionsFunc {
-	return func(gc *GatewayController) {
+	return func(gc *config) {
 		gc.clusterIssuer = issuer
 	}
 }
 
 func WithDryRun(dryrun bool) OptionsFunc {
-	return func(gc *GatewayController) {
+	return func(gc *config) {
 		gc.dryRun = dryrun
 	}
 }
 
 func WithGatewayLookupCache(glc *cache.GatewayLookupCache) OptionsFunc {
-	return func(gc *GatewayController) {
+	return func(gc *config) {
 		gc.gatewayLookupCache = glc
 	}
 }
 
 func WithHTTPSolverLabel(l string) OptionsFunc {
-	return func(gc *GatewayController) {
+	return func(gc *config) {
 		gc.httpSolverLabel = l
 	}
 }
+
+// WithEventRecorder records Kubernetes Events on Gateways for Certificate changes and failures.
+func WithEventRecorder(r record.EventRecorder) OptionsFunc {
+	return func(gc *config) {
+		gc.recorder = r
+	}
+}
+
+// WithNamespaceResolver creates Istio Gateway Certificates in the namespace of the gateway workload, where Istio
+// SDS reads credentialName Secrets from, instead of the fixed certificate namespace.
+func WithNamespaceResolver(r NamespaceResolver) OptionsFunc {
+	return func(gc *config) {
+		gc.namespaceResolver = r
+	}
+}
+
+// WithKubernetesClient is used to read client CA bundles and manage the client CA Secrets of mutual TLS servers.
+func WithKubernetesClient(client kubernetes.Interface) OptionsFunc {
+	return func(gc *config) {
+		gc.coreClient = client
+	}
+}
+
+// WithMaxSANsPerCertificate splits the hosts of a server across several Certificates when they exceed max
+// names, e.g. the 100 names Let's Encrypt allows per order.  Zero disables splitting.
+func WithMaxSANsPerCertificate(max int) OptionsFunc {
+	return func(gc *config) {
+		if max >= 0 {
+			gc.maxSANs = max
+		}
+	}
+}
+
+// WithAdoptionPolicy selects whether existing Certificates without the ManagedLabel are taken over, by default
+// they are left unchanged.
+func WithAdoptionPolicy(p AdoptionPolicy) OptionsFunc {
+	return func(gc *config) {
+		if p != "" {
+			gc.adoptionPolicy = p
+		}
+	}
+}
+
+// WithNamespaceInformer reads the default issuer and the allowed issuers of Gateways from the annotations of their
+// namespace, and requeues the Gateways of a namespace when its annotations change.
+func WithNamespaceInformer(i corev1informers.NamespaceInformer) OptionsFunc {
+	return func(gc *config) {
+		gc.namespaceLister = i.Lister()
+		gc.namespaceInformer = i.Informer()
+	}
+}
+
+// recordEvent is a no-op without an event recorder or in dry-run mode.
+func (c *config) recordEvent(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if c.recorder == nil || c.dryRun {
+		return
+	}
+
+	c.recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+}
@@ -0,0 +1,157 @@
+package gateway
+
+import (
+	"context"
+	"maps"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanagerclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+)
+
+const (
+	reasonCertificateSecretReused      = "CertificateSecretReused"
+	reasonCertificateSecretReuseFailed = "CertificateSecretReuseFailed"
+)
+
+// reuseRenamedSecret copies the Secret of a renamed Certificate to the Secret of the Certificate about to be
+// created, so cert-manager finds a valid certificate and does not issue a new one.  A port or listener rename
+// changes the credentialName, the Certificate it used is then a managed Certificate of the same Gateway, with the
+// same hosts and issuer, that no server references anymore.  It is left to garbage collection, so reuse depends on
+// the GC grace period: with --gc-grace-period=0 the old Certificate may already be deleted and the new one is
+// issued as usual.  Failures are logged and reported, the new Certificate is then issued as usual.
+func (c *config) reuseRenamedSecret(ctx context.Context, certClient certmanagerclient.Interface, gateway client.Object, namespace string, cert *v1certmanager.Certificate, inUse sets.Set[string]) {
+	if c.coreClient == nil {
+		return
+	}
+
+	log := log.FromContext(ctx)
+
+	renamed, err := c.renamedCertificate(ctx, certClient, gateway, namespace, cert, inUse)
+	if err != nil {
+		log.Error(err, "failed to List certificates, skipping secret reuse", "cert", cert.Name, "namespace", namespace)
+		return
+	}
+	if renamed == nil {
+		return
+	}
+
+	secrets := c.coreClient.CoreV1().Secrets(namespace)
+	// the Secret exists already or cannot be read
+	if _, err := secrets.Get(ctx, cert.Spec.SecretName, metav1.GetOptions{}); !k8serrors.IsNotFound(err) {
+		return
+	}
+
+	source, err := secrets.Get(ctx, renamed.Spec.SecretName, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Error(err, "failed to Get secret of renamed certificate", "secret", renamed.Spec.SecretName, "namespace", namespace)
+		}
+		return
+	}
+
+	if source.Type != corev1.SecretTypeTLS || source.Annotations[v1certmanager.CertificateNameKey] != renamed.Name {
+		return
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        cert.Spec.SecretName,
+			Namespace:   namespace,
+			Labels:      maps.Clone(source.Labels),
+			Annotations: maps.Clone(source.Annotations),
+		},
+		Type: source.Type,
+		Data: source.Data,
+	}
+	secret.Annotations[v1certmanager.CertificateNameKey] = cert.Name
+
+	createOptions := metav1.CreateOptions{FieldManager: FieldManager}
+	if c.dryRun {
+		log.Info("[dryrun] copy secret of renamed certificate", "from", source.Name, "to", secret.Name, "namespace", namespace)
+		createOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	if _, err := secrets.Create(ctx, secret, createOptions); err != nil {
+		log.Error(err, "failed to copy secret of renamed certificate", "from", source.Name, "to", secret.Name, "namespace", namespace)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateSecretReuseFailed, "Failed to copy Secret %s/%s of renamed Certificate %s to %s, a new certificate is issued: %s", namespace, source.Name, renamed.Name, secret.Name, err)
+		return
+	}
+
+	c.recordEvent(gateway, corev1.EventTypeNormal, reasonCertificateSecretReused, "Copied Secret %s/%s of renamed Certificate %s to %s", namespace, source.Name, renamed.Name, secret.Name)
+}
+
+// renamedCertificate returns the managed Certificate of the Gateway that the desired Certificate renames, or nil.
+// Shards and shared Certificates are never renamed.
+func (c *config) renamedCertificate(ctx context.Context, certClient certmanagerclient.Interface, gateway client.Object, namespace string, cert *v1certmanager.Certificate, inUse sets.Set[string]) (*v1certmanager.Certificate, error) {
+	certs, err := certClient.CertmanagerV1().Certificates(namespace).List(ctx, metav1.ListOptions{LabelSelector: v1beta1labels.ManagedLabelSelector()})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range certs.Items {
+		current := &certs.Items[i]
+		if current.Name == cert.Name || inUse.Has(current.Name) {
+			continue
+		}
+
+		if name, ns := v1beta1labels.ManagedGateway(current.Labels, current.Annotations); name != gateway.GetName() || ns != gateway.GetNamespace() {
+			continue
+		}
+
+		if current.Annotations[v1beta1labels.ManagedGroupAnnotation] != cert.Annotations[v1beta1labels.ManagedGroupAnnotation] {
+			continue
+		}
+
+		if _, ok := current.Annotations[v1beta1labels.ShardOfAnnotation]; ok {
+			continue
+		}
+		if _, ok := current.Annotations[v1beta1labels.ReferencedByAnnotation]; ok {
+			continue
+		}
+
+		if certificateHostsMatch(cert, current) && equality.Semantic.DeepEqual(cert.Spec.IssuerRef, current.Spec.IssuerRef) {
+			return current, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// serverCredentialNames returns the credentialNames referenced by the servers of a Gateway.
+func serverCredentialNames(servers []*apinetworkingv1.Server) sets.Set[string] {
+	names := sets.New[string]()
+	for _, s := range servers {
+		if s.Tls != nil && s.Tls.CredentialName != "" {
+			names.Insert(s.Tls.CredentialName)
+		}
+	}
+
+	return names
+}
+
+// listenerCertificateNames returns the Secrets referenced by the listeners of a Gateway API Gateway.
+func listenerCertificateNames(listeners []gatewayapiv1beta1.Listener) sets.Set[string] {
+	names := sets.New[string]()
+	for _, l := range listeners {
+		if l.TLS == nil {
+			continue
+		}
+
+		for _, ref := range l.TLS.CertificateRefs {
+			names.Insert(string(ref.Name))
+		}
+	}
+
+	return names
+}
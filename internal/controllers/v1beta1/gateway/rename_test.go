@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+func TestGatewayReconcile_ReusesSecretOfRenamedCertificate(t *testing.T) {
+	t.Parallel()
+
+	renamed := func(gateway string, dnsNames ...string) *v1certmanager.Certificate {
+		return &v1certmanager.Certificate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "mygateway-https",
+				Namespace:   TestCertNamespace,
+				Labels:      map[string]string{v1beta1labels.ManagedLabel: v1beta1labels.ManagedLabelValue(gateway, TestNamespace)},
+				Annotations: v1beta1labels.ManagedAnnotations(gateway, TestNamespace),
+			},
+			Spec: v1certmanager.CertificateSpec{
+				DNSNames:   dnsNames,
+				SecretName: "mygateway-https",
+				IssuerRef:  v1.ObjectReference{Kind: "ClusterIssuer", Name: "default", Group: "cert-manager.io"},
+			},
+		}
+	}
+
+	tests := []struct {
+		description string
+		cert        *v1certmanager.Certificate
+		wantReused  bool
+	}{
+		{
+			description: "port renamed",
+			cert:        renamed(TestGatewayName, "test1.example.com", "test2.example.com"),
+			wantReused:  true,
+		},
+		{
+			description: "hosts changed",
+			cert:        renamed(TestGatewayName, "test1.example.com"),
+		},
+		{
+			description: "Certificate of another Gateway",
+			cert:        renamed("other", "test1.example.com", "test2.example.com"),
+		},
+	}
+
+	for _, test := range tests {
+		source := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "mygateway-https",
+				Namespace:   TestCertNamespace,
+				Annotations: map[string]string{v1certmanager.CertificateNameKey: "mygateway-https", v1certmanager.IssuerNameAnnotationKey: "default"},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")},
+		}
+
+		helper := NewTestHelperWithGateways(AppendCertificates(test.cert))
+		coreClient := k8sfake.NewClientset(source)
+		helper.Controller.coreClient = coreClient
+		recorder := record.NewFakeRecorder(10)
+		helper.Controller.recorder = recorder
+
+		assertCreateCertificateCalled(t, helper)
+
+		secret, err := coreClient.CoreV1().Secrets(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+		if !test.wantReused {
+			assert.True(t, k8serrors.IsNotFound(err), test.description)
+			assert.Contains(t, <-recorder.Events, "Normal CertificateCreated", test.description)
+			continue
+		}
+
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, source.Data, secret.Data, test.description)
+		assert.Equal(t, corev1.SecretTypeTLS, secret.Type, test.description)
+		assert.Equal(t, TestCertificateName, secret.Annotations[v1certmanager.CertificateNameKey], test.description)
+		assert.Equal(t, "default", secret.Annotations[v1certmanager.IssuerNameAnnotationKey], test.description)
+		assert.Contains(t, <-recorder.Events, "Normal CertificateSecretReused", test.description)
+	}
+}
+
+func TestGatewayReconcile_RenamedCertificateAlreadyCollected(t *testing.T) {
+	t.Parallel()
+
+	// with --gc-grace-period=0 the Certificate of the old port name is deleted right away, only its Secret is left
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mygateway-https",
+			Namespace:   TestCertNamespace,
+			Annotations: map[string]string{v1certmanager.CertificateNameKey: "mygateway-https"},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")},
+	}
+
+	helper := NewTestHelperWithGateways()
+	coreClient := k8sfake.NewClientset(source)
+	helper.Controller.coreClient = coreClient
+	recorder := record.NewFakeRecorder(10)
+	helper.Controller.recorder = recorder
+
+	assertCreateCertificateCalled(t, helper)
+
+	_, err := coreClient.CoreV1().Secrets(TestCertNamespace).Get(context.TODO(), TestCertificateName, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	assert.Contains(t, <-recorder.Events, "Normal CertificateCreated")
+	assert.Len(t, recorder.Events, 0)
+}
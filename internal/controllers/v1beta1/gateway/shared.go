@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+)
+
+const (
+	reasonCertificateShared      = "CertificateShared"
+	reasonCertificateShareFailed = "CertificateShareFailed"
+)
+
+// gatewayReference returns the reference to the Gateway listed in the ReferencedByAnnotation.
+func gatewayReference(gateway client.Object) string {
+	return v1beta1labels.GatewayReference(gateway.GetName(), gateway.GetNamespace())
+}
+
+// referencedBy returns the sorted ReferencedByAnnotation value of a shared Certificate with the Gateway added to
+// the Gateways already referencing the current Certificate.
+func referencedBy(current *v1certmanager.Certificate, gateway client.Object) string {
+	refs := sets.New(gatewayReference(gateway))
+	if current != nil {
+		refs.Insert(v1beta1labels.ParseReferencedBy(current.Annotations[v1beta1labels.ReferencedByAnnotation])...)
+	}
+
+	return strings.Join(sets.List(refs), ",")
+}
+
+// isPrimaryReference reports whether the Gateway applies a shared Certificate.  The Gateway the Certificate is
+// managed for, or any Gateway when the Certificate does not exist yet or that Gateway no longer references it,
+// applies the Certificate, so Gateways with different Certificate annotations do not fight over it.
+func isPrimaryReference(current *v1certmanager.Certificate, gateway client.Object) bool {
+	if current == nil {
+		return true
+	}
+
+	name, namespace := v1beta1labels.ManagedGateway(current.Labels, current.Annotations)
+	if name == "" {
+		return true
+	}
+
+	primary := v1beta1labels.GatewayReference(name, namespace)
+	return primary == gatewayReference(gateway) ||
+		!slices.Contains(v1beta1labels.ParseReferencedBy(current.Annotations[v1beta1labels.ReferencedByAnnotation]), primary)
+}
+
+// addReference adds a Gateway that does not apply a shared Certificate to its ReferencedByAnnotation, so the
+// garbage collection controller keeps the Certificate while the Gateway uses it.
+func (c *GatewayController) addReference(ctx context.Context, gateway client.Object, current *v1certmanager.Certificate) error {
+	log := log.FromContext(ctx)
+
+	if slices.Contains(v1beta1labels.ParseReferencedBy(current.Annotations[v1beta1labels.ReferencedByAnnotation]), gatewayReference(gateway)) {
+		return nil
+	}
+
+	refs := referencedBy(current, gateway)
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{v1beta1labels.ReferencedByAnnotation: refs},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	patchOptions := metav1.PatchOptions{FieldManager: FieldManager}
+	if c.dryRun {
+		log.Info("[dryrun] add shared certificate reference", "cert", current.Name, "namespace", current.Namespace, "gateway", gatewayReference(gateway))
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+	}
+
+	if _, err := c.certClient.CertmanagerV1().Certificates(current.Namespace).Patch(ctx, current.Name, types.MergePatchType, patch, patchOptions); err != nil {
+		log.Error(err, "error adding shared certificate reference", "cert", current.Name, "namespace", current.Namespace)
+		c.recordEvent(gateway, corev1.EventTypeWarning, reasonCertificateShareFailed, "Failed to share Certificate %s/%s: %s", current.Namespace, current.Name, err)
+		return err
+	}
+
+	c.recordEvent(gateway, corev1.EventTypeNormal, reasonCertificateShared, "Sharing Certificate %s/%s with Gateways %s", current.Namespace, current.Name, refs)
+	return nil
+}
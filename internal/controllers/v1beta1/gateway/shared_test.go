@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "istio.io/api/networking/v1"
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+func newSharedGateway(name string, annotations map[string]string, hosts ...string) *istiov1.Gateway {
+	gateway := &istiov1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   TestNamespace,
+			Labels:      map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+			Annotations: annotations,
+		},
+		Spec: networkingv1.Gateway{
+			Servers: []*networkingv1.Server{{
+				Hosts: hosts,
+				Port:  &networkingv1.Port{Number: 443, Name: "https"},
+				Tls:   &networkingv1.ServerTLSSettings{Mode: networkingv1.ServerTLSSettings_SIMPLE},
+			}},
+		},
+	}
+
+	issuer, _ := naming.ResolveIssuer("default", nil, gateway)
+	gateway.Spec.Servers[0].Tls.CredentialName, _ = naming.SharedCredentialName(hosts, issuer)
+	return gateway
+}
+
+func TestGatewayReconcile_SharesCertificate(t *testing.T) {
+	t.Parallel()
+
+	blue := newSharedGateway("blue", map[string]string{v1beta1labels.DurationAnnotation: "2160h"}, "a.example.com", "b.example.com")
+	green := newSharedGateway("green", map[string]string{v1beta1labels.DurationAnnotation: "720h"}, "b.example.com", "a.example.com")
+	name := blue.Spec.Servers[0].Tls.CredentialName
+	assert.Equal(t, name, green.Spec.Servers[0].Tls.CredentialName)
+
+	istioClient := istiofake.NewSimpleClientset()
+	for _, gw := range []*istiov1.Gateway{blue, green} {
+		_, err := istioClient.NetworkingV1().Gateways(TestNamespace).Create(context.TODO(), gw, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	certClient := newTestCertClient()
+	recorder := record.NewFakeRecorder(10)
+	controller := NewGatewayController(istioClient, certClient, WithCertificateNamespace(TestCertNamespace), WithEventRecorder(recorder))
+
+	for _, gw := range []string{"blue", "green", "blue"} {
+		_, err := controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: gw, Namespace: TestNamespace}})
+		assert.NoError(t, err, gw)
+	}
+
+	certs, err := certClient.CertmanagerV1().Certificates(TestCertNamespace).List(context.TODO(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, certs.Items, 1)
+
+	cert := certs.Items[0]
+	assert.Equal(t, name, cert.Name)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, cert.Spec.DNSNames)
+	assert.Equal(t, "blue.test,green.test", cert.Annotations[v1beta1labels.ReferencedByAnnotation])
+
+	// the Certificate is applied by the Gateway in the ManagedLabel only
+	assert.Equal(t, v1beta1labels.ManagedLabelValue("blue", TestNamespace), cert.Labels[v1beta1labels.ManagedLabel])
+	assert.Equal(t, "2160h0m0s", cert.Spec.Duration.Duration.String())
+
+	assert.Contains(t, <-recorder.Events, "Normal CertificateCreated")
+	assert.Contains(t, <-recorder.Events, "Normal CertificateShared")
+	assert.Len(t, recorder.Events, 0)
+}
+
+func TestGatewayReconcile_TakesOverSharedCertificate(t *testing.T) {
+	t.Parallel()
+
+	green := newSharedGateway("green", nil, "a.example.com")
+	name := green.Spec.Servers[0].Tls.CredentialName
+
+	istioClient := istiofake.NewSimpleClientset()
+	_, err := istioClient.NetworkingV1().Gateways(TestNamespace).Create(context.TODO(), green, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// blue no longer references the Certificate it applied
+	certClient := newTestCertClient()
+	controller := NewGatewayController(istioClient, certClient, WithCertificateNamespace(TestCertNamespace))
+	current := controller.newCertificate(context.TODO(), name, newSharedGateway("blue", nil, "a.example.com"), certificateHosts{dnsNames: []string{"a.example.com"}}, nil)
+	current.Annotations[v1beta1labels.ReferencedByAnnotation] = "green.test"
+	_, err = certClient.CertmanagerV1().Certificates(TestCertNamespace).Create(context.TODO(), current, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	_, err = controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "green", Namespace: TestNamespace}})
+	assert.NoError(t, err)
+
+	cert, err := certClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1beta1labels.ManagedLabelValue("green", TestNamespace), cert.Labels[v1beta1labels.ManagedLabel])
+	assert.Equal(t, "green", cert.Annotations[v1beta1labels.ManagedNameAnnotation])
+	assert.Equal(t, "green.test", cert.Annotations[v1beta1labels.ReferencedByAnnotation])
+}
+
+func TestIsSharedServer(t *testing.T) {
+	t.Parallel()
+
+	server := newSharedGateway("blue", nil, "a.example.com").Spec.Servers[0]
+	assert.True(t, isSharedServer(server))
+
+	server.Tls.Mode = networkingv1.ServerTLSSettings_MUTUAL
+	assert.False(t, isSharedServer(server))
+
+	server.Tls.Mode = networkingv1.ServerTLSSettings_SIMPLE
+	server.Tls.CredentialName = "https-a-example-com"
+	assert.False(t, isSharedServer(server))
+}
+
+func TestGatewayReconcile_SharedAfterNamespaceIssuerChange(t *testing.T) {
+	t.Parallel()
+
+	// both Gateways were admitted before the namespace default issuer was set
+	blue := newSharedGateway("blue", nil, "a.example.com")
+	green := newSharedGateway("green", nil, "a.example.com")
+	name := blue.Spec.Servers[0].Tls.CredentialName
+
+	istioClient := istiofake.NewSimpleClientset()
+	for _, gw := range []*istiov1.Gateway{blue, green} {
+		_, err := istioClient.NetworkingV1().Gateways(TestNamespace).Create(context.TODO(), gw, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	certClient := newTestCertClient()
+	controller := NewGatewayController(istioClient, certClient, WithCertificateNamespace(TestCertNamespace))
+	controller.namespaceLister = newTestNamespaceLister(t, map[string]string{v1beta1labels.IssuerAnnotation: "team-ca"})
+
+	for _, gw := range []string{"blue", "green", "blue"} {
+		_, err := controller.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: gw, Namespace: TestNamespace}})
+		assert.NoError(t, err, gw)
+	}
+
+	cert, err := certClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, v1beta1labels.ManagedLabelValue("blue", TestNamespace), cert.Labels[v1beta1labels.ManagedLabel])
+	assert.Equal(t, "blue.test,green.test", cert.Annotations[v1beta1labels.ReferencedByAnnotation])
+	assert.Equal(t, v1.ObjectReference{Name: "team-ca", Kind: "ClusterIssuer", Group: "cert-manager.io"}, cert.Spec.IssuerRef)
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/naming"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+const (
+	reasonUnmanagedSecretMissing = "UnmanagedServerSecretMissing"
+	reasonUnmanagedSecretInvalid = "UnmanagedServerSecretInvalid"
+)
+
+// splitUnmanagedServers splits the servers of a Gateway into the servers given a Certificate, along with servers
+// without a managed TLS mode, and the unmanaged servers.
+func splitUnmanagedServers(gateway *networkingv1.Gateway) ([]*apinetworkingv1.Server, []*apinetworkingv1.Server) {
+	servers := []*apinetworkingv1.Server{}
+	unmanaged := []*apinetworkingv1.Server{}
+	for _, s := range gateway.Spec.Servers {
+		if s.Tls != nil && naming.IsManagedTLSMode(s.Tls.Mode) && naming.IsUnmanagedServer(gateway, s.Port.GetName(), s.Hosts) {
+			unmanaged = append(unmanaged, s)
+		} else {
+			servers = append(servers, s)
+		}
+	}
+
+	return servers, unmanaged
+}
+
+// managedListeners returns the listeners of a Gateway API Gateway that are not unmanaged.
+func managedListeners(gateway *gatewayapiv1beta1.Gateway) []gatewayapiv1beta1.Listener {
+	listeners := []gatewayapiv1beta1.Listener{}
+	for _, l := range gateway.Spec.Listeners {
+		if !naming.IsUnmanagedListener(gateway, l) {
+			listeners = append(listeners, l)
+		}
+	}
+
+	return listeners
+}
+
+// unmanagedListeners returns the terminating TLS listeners of a Gateway API Gateway that are unmanaged.
+func unmanagedListeners(gateway *gatewayapiv1beta1.Gateway) []*gatewayapiv1beta1.Listener {
+	listeners := []*gatewayapiv1beta1.Listener{}
+	for i := range gateway.Spec.Listeners {
+		l := &gateway.Spec.Listeners[i]
+		if _, ok := listenerCertificateName(l); ok && naming.IsUnmanagedListener(gateway, *l) {
+			listeners = append(listeners, l)
+		}
+	}
+
+	return listeners
+}
+
+// checkUnmanagedSecret reports, with an Event on the Gateway, an unmanaged server or listener whose Secret is
+// missing or does not hold a certificate valid for every host.  Istio reads the Secret of a server from the namespace
+// of the gateway workload, and the Secret of a listener from the namespace of the Gateway.  The Event is only
+// recorded when the state of the Secret changes, so the periodic resync does not repeat it.  The check only
+// reports, it does not requeue the Gateway.
+func (c *config) checkUnmanagedSecret(ctx context.Context, gateway client.Object, namespace, name, server string, hosts []string) {
+	if c.coreClient == nil || name == "" {
+		return
+	}
+
+	log := log.FromContext(ctx)
+	key := strings.Join([]string{gateway.GetNamespace(), gateway.GetName(), server, namespace, name}, "/")
+
+	secret, err := c.coreClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		log.Info("unmanaged server Secret does not exist", "server", server, "secret", name, "namespace", namespace)
+		if c.unmanagedSecretStateChanged(key, reasonUnmanagedSecretMissing) {
+			c.recordEvent(gateway, corev1.EventTypeWarning, reasonUnmanagedSecretMissing, "Secret %s/%s of unmanaged server %s does not exist", namespace, name, server)
+		}
+		return
+	} else if err != nil {
+		log.Error(err, "failed to Get unmanaged server Secret", "secret", name, "namespace", namespace)
+		return
+	}
+
+	if err := secretCoversHosts(secret, hosts); err != nil {
+		log.Info("unmanaged server Secret is invalid", "server", server, "secret", name, "namespace", namespace, "error", err.Error())
+		if c.unmanagedSecretStateChanged(key, err.Error()) {
+			c.recordEvent(gateway, corev1.EventTypeWarning, reasonUnmanagedSecretInvalid, "Secret %s/%s of unmanaged server %s: %s", namespace, name, server, err)
+		}
+		return
+	}
+
+	c.unmanagedSecretStates.Delete(key)
+}
+
+// unmanagedSecretStateChanged records the state of an unmanaged Secret and reports whether it differs from the
+// previously recorded state.
+func (c *config) unmanagedSecretStateChanged(key, state string) bool {
+	previous, loaded := c.unmanagedSecretStates.Swap(key, state)
+	return !loaded || previous != state
+}
+
+// secretCoversHosts returns an error unless the leaf certificate in the tls.crt key of the Secret is valid for
+// every issuable host.
+func secretCoversHosts(secret *corev1.Secret, hosts []string) error {
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return fmt.Errorf("%s does not hold a PEM encoded certificate", corev1.TLSCertKey)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", corev1.TLSCertKey, err)
+	}
+
+	normalized := normalizeHosts(hosts, true)
+	uncovered := []string{}
+	for _, h := range append(normalized.dnsNames, normalized.ipAddresses...) {
+		if err := cert.VerifyHostname(h); err != nil {
+			uncovered = append(uncovered, h)
+		}
+	}
+
+	if len(uncovered) > 0 {
+		return fmt.Errorf("the certificate is not valid for %s", strings.Join(uncovered, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "istio.io/api/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// newTestTLSSecret returns a TLS Secret holding a self-signed certificate for dnsNames.
+func newTestTLSSecret(t *testing.T, name string, dnsNames ...string) *corev1.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: TestCertNamespace},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})},
+	}
+}
+
+func TestGatewayReconcile_SkipsUnmanagedServer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		secrets     []runtime.Object
+		wantEvent   string
+	}{
+		{
+			description: "Secret missing",
+			wantEvent:   "Warning UnmanagedServerSecretMissing",
+		},
+		{
+			description: "Secret for other hosts",
+			secrets:     []runtime.Object{newTestTLSSecret(t, "ev-cert", "other.example.com")},
+			wantEvent:   "Warning UnmanagedServerSecretInvalid",
+		},
+		{
+			description: "Secret covering the hosts",
+			secrets:     []runtime.Object{newTestTLSSecret(t, "ev-cert", "*.example.com")},
+		},
+	}
+
+	for _, test := range tests {
+		helper := NewTestHelperWithGateways(
+			WithAnnotations(map[string]string{v1beta1labels.UnmanagedServersAnnotation: "https-ev"}),
+			AppendServer(&networkingv1.Server{
+				Hosts: []string{"www.example.com"},
+				Port:  &networkingv1.Port{Number: 8443, Name: "https-ev"},
+				Tls:   &networkingv1.ServerTLSSettings{Mode: networkingv1.ServerTLSSettings_SIMPLE, CredentialName: "ev-cert"},
+			}))
+		helper.Controller.coreClient = k8sfake.NewClientset(test.secrets...)
+		recorder := record.NewFakeRecorder(10)
+		helper.Controller.recorder = recorder
+
+		assertCreateCertificateCalled(t, helper)
+
+		_, err := helper.CertClient.CertmanagerV1().Certificates(TestCertNamespace).Get(context.TODO(), "ev-cert", metav1.GetOptions{})
+		assert.Error(t, err, test.description)
+
+		assert.Contains(t, <-recorder.Events, "Normal CertificateCreated", test.description)
+		if test.wantEvent == "" {
+			assert.Len(t, recorder.Events, 0, test.description)
+		} else {
+			assert.Contains(t, <-recorder.Events, test.wantEvent, test.description)
+		}
+
+		// the resync does not repeat the event
+		_, err = helper.Controller.Reconcile(context.TODO(), reconcileRequest())
+		assert.NoError(t, err, test.description)
+		for len(recorder.Events) > 0 {
+			assert.NotContains(t, <-recorder.Events, "UnmanagedServerSecret", test.description)
+		}
+	}
+}
+
+func TestGatewayAPIReconcile_SkipsUnmanagedListener(t *testing.T) {
+	t.Parallel()
+
+	gateway := newTestGatewayAPIGateway(
+		map[string]string{v1beta1labels.InjectSimpleCredentialNameLabel: "true"},
+		map[string]string{v1beta1labels.UnmanagedServersAnnotation: "https-ev"},
+		newTestListener("https-ev", "www.example.com", "ev-cert", gatewayapiv1beta1.TLSModeTerminate),
+	)
+
+	c := newTestGatewayAPIController(t, gateway)
+	coreClient := k8sfake.NewClientset()
+	c.coreClient = coreClient
+	recorder := record.NewFakeRecorder(10)
+	c.recorder = recorder
+
+	reconcile := func() {
+		_, err := c.Reconcile(context.TODO(), reconcileRequest())
+		assert.NoError(t, err)
+	}
+
+	reconcile()
+	_, err := c.certClient.CertmanagerV1().Certificates(TestNamespace).Get(context.TODO(), "ev-cert", metav1.GetOptions{})
+	assert.Error(t, err)
+	assert.Contains(t, <-recorder.Events, "Warning UnmanagedServerSecretMissing")
+
+	reconcile()
+	assert.Len(t, recorder.Events, 0)
+
+	// a Secret for other hosts changes the state
+	secret := newTestTLSSecret(t, "ev-cert", "other.example.com")
+	secret.Namespace = TestNamespace
+	_, err = coreClient.CoreV1().Secrets(TestNamespace).Create(context.TODO(), secret, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	reconcile()
+	assert.Contains(t, <-recorder.Events, "Warning UnmanagedServerSecretInvalid")
+
+	// a valid Secret clears the state, a later failure is reported again
+	secret = newTestTLSSecret(t, "ev-cert", "*.example.com")
+	secret.Namespace = TestNamespace
+	_, err = coreClient.CoreV1().Secrets(TestNamespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	reconcile()
+	assert.Len(t, recorder.Events, 0)
+
+	assert.NoError(t, coreClient.CoreV1().Secrets(TestNamespace).Delete(context.TODO(), "ev-cert", metav1.DeleteOptions{}))
+	reconcile()
+	assert.Contains(t, <-recorder.Events, "Warning UnmanagedServerSecretMissing")
+}
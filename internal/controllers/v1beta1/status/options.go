@@ -0,0 +1,20 @@
+package status
+
+import (
+	gatewayapiclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+)
+
+type OptionsFunc func(*StatusController)
+
+func WithDryRun(dryrun bool) OptionsFunc {
+	return func(sc *StatusController) {
+		sc.dryRun = dryrun
+	}
+}
+
+// WithGatewayAPIClient enables status reporting onto Gateway API Gateways.
+func WithGatewayAPIClient(client gatewayapiclient.Interface) OptionsFunc {
+	return func(sc *StatusController) {
+		sc.gatewayAPIClient = client
+	}
+}
@@ -0,0 +1,323 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	certmanagerversionedclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	certmanagerinformers "github.com/cert-manager/cert-manager/pkg/client/informers/externalversions"
+	v1beta1controllers "github.com/kanopy-platform/gateway-certificate-controller/internal/controllers/v1beta1/gateway"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	istioversionedclient "istio.io/client-go/pkg/clientset/versioned"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8scache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapiclient "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+// ServerStatus summarizes the Certificate managed for a single Gateway server or listener.
+type ServerStatus struct {
+	Certificate     string       `json:"certificate"`
+	Ready           bool         `json:"ready"`
+	NotAfter        *metav1.Time `json:"notAfter,omitempty"`
+	Reason          string       `json:"reason,omitempty"`
+	Message         string       `json:"message,omitempty"`
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
+	Issuer          string       `json:"issuer"`
+	Shards          int          `json:"shards,omitempty"`
+	Warning         string       `json:"warning,omitempty"`
+}
+
+// gatewayIndex indexes managed Certificates by the ManagedLabelValue of every Gateway using them.
+const gatewayIndex = "managingGateway"
+
+// StatusController watches managed Certificates and writes a status summary, keyed by server port name or
+// listener name, onto the owning Gateway in the StatusAnnotation.
+type StatusController struct {
+	name                string
+	certmanagerClient   certmanagerversionedclient.Interface
+	istioClient         istioversionedclient.Interface
+	gatewayAPIClient    gatewayapiclient.Interface
+	dryRun              bool
+	certInformerFactory certmanagerinformers.SharedInformerFactory
+	certificates        k8scache.SharedIndexInformer
+}
+
+func NewStatusController(istioClient istioversionedclient.Interface, certClient certmanagerversionedclient.Interface, opts ...OptionsFunc) *StatusController {
+	sc := &StatusController{
+		name:              "istio-certificate-status-controller",
+		certmanagerClient: certClient,
+		istioClient:       istioClient,
+	}
+
+	sc.certInformerFactory = certmanagerinformers.NewSharedInformerFactoryWithOptions(certClient, time.Second*30, certmanagerinformers.WithTweakListOptions(func(listOptions *metav1.ListOptions) {
+		listOptions.LabelSelector = v1beta1labels.ManagedLabelSelector()
+	}))
+	sc.certificates = sc.certInformerFactory.Certmanager().V1().Certificates().Informer()
+	// the informer has not started, AddIndexers only fails once it runs
+	_ = sc.certificates.AddIndexers(k8scache.Indexers{gatewayIndex: indexByManagingGateway})
+
+	for _, opt := range opts {
+		opt(sc)
+	}
+
+	return sc
+}
+
+func (c *StatusController) SetupWithManager(ctx context.Context, mgr manager.Manager) error {
+	ctrl, err := controller.New(c.name, mgr, controller.Options{
+		Reconciler: c,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reconcile reads Certificates from the informer cache, the watch waits for it to sync
+	if err := ctrl.Watch(&source.Informer{
+		Informer: c.certificates,
+		Handler:  handler.EnqueueRequestsFromMapFunc(gatewayForCertificate),
+	}); err != nil {
+		return err
+	}
+
+	c.certInformerFactory.Start(ctx.Done())
+
+	return nil
+}
+
+// gatewayForCertificate maps a managed Certificate to the Gateway named in its ManagedLabel, or every Gateway
+// referencing a shared Certificate.
+func gatewayForCertificate(ctx context.Context, obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, ref := range v1beta1labels.ManagingGateways(obj.GetLabels(), obj.GetAnnotations()) {
+		name, namespace := v1beta1labels.ParseManagedLabel(ref)
+		if name == "" || namespace == "" {
+			continue
+		}
+
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}})
+	}
+
+	return requests
+}
+
+// indexByManagingGateway returns the ManagedLabelValue of every Gateway using a managed Certificate, see
+// v1beta1labels.ManagingGateways.
+func indexByManagingGateway(obj interface{}) ([]string, error) {
+	cert, ok := obj.(*v1certmanager.Certificate)
+	if !ok {
+		return nil, nil
+	}
+
+	keys := []string{}
+	for _, ref := range v1beta1labels.ManagingGateways(cert.Labels, cert.Annotations) {
+		name, namespace := v1beta1labels.ParseManagedLabel(ref)
+		if name == "" || namespace == "" {
+			continue
+		}
+
+		keys = append(keys, v1beta1labels.ManagedLabelValue(name, namespace))
+	}
+
+	return keys, nil
+}
+
+func (c *StatusController) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	log.V(1).Info("Reconciling Gateway certificate status", "request", request.String())
+
+	// shared Certificates are labeled with a single Gateway, so they are looked up by every Gateway using them
+	objs, err := c.certificates.GetIndexer().ByIndex(gatewayIndex, v1beta1labels.ManagedLabelValue(request.Name, request.Namespace))
+	if err != nil {
+		log.Error(err, "failed to List Certificates")
+		return reconcile.Result{
+			Requeue: true,
+		}, err
+	}
+
+	istioCerts := map[string]*v1certmanager.Certificate{}
+	gatewayAPICerts := map[string]*v1certmanager.Certificate{}
+	for _, obj := range objs {
+		cert, ok := obj.(*v1certmanager.Certificate)
+		if !ok {
+			continue
+		}
+
+		if cert.Annotations[v1beta1labels.ManagedGroupAnnotation] == gatewayapiv1beta1.GroupName {
+			// Gateway API Certificates live in the namespace of the Gateway
+			if cert.Namespace == request.Namespace {
+				gatewayAPICerts[cert.Name] = cert
+			}
+		} else {
+			istioCerts[cert.Name] = cert
+		}
+	}
+
+	if err := c.reconcileGateway(ctx, request, istioCerts); err != nil {
+		log.Error(err, "failed to update Gateway status", "gateway", request.String())
+		return reconcile.Result{
+			Requeue: true,
+		}, err
+	}
+
+	if c.gatewayAPIClient != nil {
+		if err := c.reconcileGatewayAPIGateway(ctx, request, gatewayAPICerts); err != nil {
+			log.Error(err, "failed to update Gateway API Gateway status", "gateway", request.String())
+			return reconcile.Result{
+				Requeue: true,
+			}, err
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+func (c *StatusController) reconcileGateway(ctx context.Context, request reconcile.Request, certs map[string]*v1certmanager.Certificate) error {
+	gateway, err := c.istioClient.NetworkingV1().Gateways(request.Namespace).Get(ctx, request.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	servers := map[string]ServerStatus{}
+	for _, s := range gateway.Spec.Servers {
+		if s.Tls == nil || s.Tls.CredentialName == "" {
+			continue
+		}
+
+		cert, ok := certs[s.Tls.CredentialName]
+		if !ok {
+			continue
+		}
+
+		key := s.Tls.CredentialName
+		if s.Port != nil && s.Port.Name != "" {
+			key = s.Port.Name
+		}
+
+		servers[key] = certificateStatus(cert)
+	}
+
+	patch, err := statusPatch(gateway.Annotations, servers)
+	if err != nil || patch == nil {
+		return err
+	}
+
+	_, err = c.istioClient.NetworkingV1().Gateways(gateway.Namespace).Patch(ctx, gateway.Name, types.MergePatchType, patch, c.patchOptions())
+	return err
+}
+
+func (c *StatusController) reconcileGatewayAPIGateway(ctx context.Context, request reconcile.Request, certs map[string]*v1certmanager.Certificate) error {
+	gateway, err := c.gatewayAPIClient.GatewayV1beta1().Gateways(request.Namespace).Get(ctx, request.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	listeners := map[string]ServerStatus{}
+	for _, l := range gateway.Spec.Listeners {
+		if l.TLS == nil || len(l.TLS.CertificateRefs) == 0 || l.TLS.CertificateRefs[0].Namespace != nil {
+			continue
+		}
+
+		cert, ok := certs[string(l.TLS.CertificateRefs[0].Name)]
+		if !ok {
+			continue
+		}
+
+		listeners[string(l.Name)] = certificateStatus(cert)
+	}
+
+	patch, err := statusPatch(gateway.Annotations, listeners)
+	if err != nil || patch == nil {
+		return err
+	}
+
+	_, err = c.gatewayAPIClient.GatewayV1beta1().Gateways(gateway.Namespace).Patch(ctx, gateway.Name, types.MergePatchType, patch, c.patchOptions())
+	return err
+}
+
+func (c *StatusController) patchOptions() metav1.PatchOptions {
+	patchOptions := metav1.PatchOptions{FieldManager: v1beta1controllers.FieldManager}
+	if c.dryRun {
+		patchOptions.DryRun = []string{metav1.DryRunAll}
+	}
+	return patchOptions
+}
+
+// certificateStatus summarizes the Ready condition, expiry and issuer of a Certificate.
+func certificateStatus(cert *v1certmanager.Certificate) ServerStatus {
+	status := ServerStatus{
+		Certificate:     cert.Name,
+		NotAfter:        cert.Status.NotAfter,
+		LastFailureTime: cert.Status.LastFailureTime,
+		Issuer:          fmt.Sprintf("%s/%s", cert.Spec.IssuerRef.Kind, cert.Spec.IssuerRef.Name),
+	}
+
+	// Istio serves a single credential per server, hosts split into the other shards are not served
+	if shards, err := strconv.Atoi(cert.Annotations[v1beta1labels.ShardCountAnnotation]); err == nil && shards > 1 {
+		status.Shards = shards
+		status.Warning = fmt.Sprintf("hosts exceed the maximum names per Certificate and were split into %d Certificates, only the hosts of %s are served", shards, cert.Name)
+	}
+
+	for _, condition := range cert.Status.Conditions {
+		if condition.Type != v1certmanager.CertificateConditionReady {
+			continue
+		}
+
+		status.Ready = condition.Status == cmmeta.ConditionTrue
+		if !status.Ready {
+			status.Reason = condition.Reason
+			status.Message = condition.Message
+		}
+	}
+
+	return status
+}
+
+// statusPatch returns a merge patch setting the StatusAnnotation to the encoded summary, or removing it
+// when there is nothing to report.  A nil patch is returned when the annotation is already up to date.
+func statusPatch(annotations map[string]string, servers map[string]ServerStatus) ([]byte, error) {
+	current, exists := annotations[v1beta1labels.StatusAnnotation]
+
+	var value *string
+	if len(servers) > 0 {
+		b, err := json.Marshal(servers)
+		if err != nil {
+			return nil, err
+		}
+
+		s := string(b)
+		if exists && current == s {
+			return nil, nil
+		}
+		value = &s
+	} else if !exists {
+		return nil, nil
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{
+				v1beta1labels.StatusAnnotation: value,
+			},
+		},
+	})
+}
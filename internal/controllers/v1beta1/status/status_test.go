@@ -0,0 +1,300 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	certmanagerfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+	gatewayapifake "sigs.k8s.io/gateway-api/pkg/client/clientset/versioned/fake"
+
+	v1certmanager "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+// metav1.Time decodes to local time
+var notAfter = metav1.NewTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).Local())
+
+func newTestCertificate(name, namespace string, ready bool) *v1certmanager.Certificate {
+	condition := v1certmanager.CertificateCondition{
+		Type:   v1certmanager.CertificateConditionReady,
+		Status: cmmeta.ConditionTrue,
+		Reason: "Ready",
+	}
+
+	if !ready {
+		condition.Status = cmmeta.ConditionFalse
+		condition.Reason = "Failed"
+		condition.Message = "issuer not found"
+	}
+
+	return &v1certmanager.Certificate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{v1beta1labels.ManagedLabel: "gateway-123.devops"},
+		},
+		Spec: v1certmanager.CertificateSpec{
+			IssuerRef: cmmeta.ObjectReference{Kind: "ClusterIssuer", Name: "selfsigned"},
+		},
+		Status: v1certmanager.CertificateStatus{
+			Conditions: []v1certmanager.CertificateCondition{condition},
+			NotAfter:   &notAfter,
+		},
+	}
+}
+
+func newTestGateway(annotations map[string]string) *networkingv1.Gateway {
+	return &networkingv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "gateway-123",
+			Namespace:   "devops",
+			Annotations: annotations,
+		},
+		Spec: apinetworkingv1.Gateway{
+			Servers: []*apinetworkingv1.Server{
+				{
+					Port: &apinetworkingv1.Port{Name: "https", Number: 443},
+					Tls:  &apinetworkingv1.ServerTLSSettings{CredentialName: "devops-gateway-123-https"},
+				},
+				{
+					Port: &apinetworkingv1.Port{Name: "https-alt", Number: 8443},
+					Tls:  &apinetworkingv1.ServerTLSSettings{CredentialName: "devops-gateway-123-https-alt"},
+				},
+				{
+					Port: &apinetworkingv1.Port{Name: "http", Number: 80},
+				},
+			},
+		},
+	}
+}
+
+// newTestIstioClient creates the Gateways through the typed client so they are tracked as networking.istio.io/v1
+func newTestIstioClient(t *testing.T, gateways ...*networkingv1.Gateway) *istiofake.Clientset {
+	ic := istiofake.NewSimpleClientset()
+	for _, gateway := range gateways {
+		_, err := ic.NetworkingV1().Gateways(gateway.Namespace).Create(context.TODO(), gateway, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+	return ic
+}
+
+// newTestStatusController adds the Certificates to the informer cache Reconcile reads them from.
+func newTestStatusController(t *testing.T, istioClient *istiofake.Clientset, certs []*v1certmanager.Certificate, opts ...OptionsFunc) *StatusController {
+	sc := NewStatusController(istioClient, certmanagerfake.NewSimpleClientset(), opts...)
+	for _, cert := range certs {
+		assert.NoError(t, sc.certificates.GetIndexer().Add(cert))
+	}
+	return sc
+}
+
+func reconcileRequest() reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Name: "gateway-123", Namespace: "devops"}}
+}
+
+func decodeStatus(t *testing.T, annotations map[string]string) map[string]ServerStatus {
+	servers := map[string]ServerStatus{}
+	assert.NoError(t, json.Unmarshal([]byte(annotations[v1beta1labels.StatusAnnotation]), &servers))
+	return servers
+}
+
+func TestStatusControllerReconcile(t *testing.T) {
+	t.Parallel()
+
+	istioClient := newTestIstioClient(t, newTestGateway(nil))
+	sc := newTestStatusController(t, istioClient, []*v1certmanager.Certificate{
+		newTestCertificate("devops-gateway-123-https", "cert-manager", true),
+		newTestCertificate("devops-gateway-123-https-alt", "cert-manager", false),
+	})
+	result, err := sc.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+
+	gateway, err := istioClient.NetworkingV1().Gateways("devops").Get(context.TODO(), "gateway-123", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]ServerStatus{
+		"https": {
+			Certificate: "devops-gateway-123-https",
+			Ready:       true,
+			NotAfter:    &notAfter,
+			Issuer:      "ClusterIssuer/selfsigned",
+		},
+		"https-alt": {
+			Certificate: "devops-gateway-123-https-alt",
+			Ready:       false,
+			NotAfter:    &notAfter,
+			Reason:      "Failed",
+			Message:     "issuer not found",
+			Issuer:      "ClusterIssuer/selfsigned",
+		},
+	}, decodeStatus(t, gateway.Annotations))
+}
+
+func TestCertificateStatusShards(t *testing.T) {
+	t.Parallel()
+
+	cert := newTestCertificate("devops-gateway-123-https", "cert-manager", true)
+	assert.Zero(t, certificateStatus(cert).Shards)
+	assert.Empty(t, certificateStatus(cert).Warning)
+
+	cert.Annotations = map[string]string{v1beta1labels.ShardCountAnnotation: "3"}
+	status := certificateStatus(cert)
+	assert.Equal(t, 3, status.Shards)
+	assert.Contains(t, status.Warning, "split into 3 Certificates")
+}
+
+func TestStatusControllerReconcileRemovesStatus(t *testing.T) {
+	t.Parallel()
+
+	istioClient := newTestIstioClient(t, newTestGateway(map[string]string{
+		v1beta1labels.StatusAnnotation: `{"https":{"certificate":"devops-gateway-123-https","ready":true,"issuer":"ClusterIssuer/selfsigned"}}`,
+		"keep":                         "me",
+	}))
+
+	sc := newTestStatusController(t, istioClient, nil)
+	_, err := sc.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	gateway, err := istioClient.NetworkingV1().Gateways("devops").Get(context.TODO(), "gateway-123", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"keep": "me"}, gateway.Annotations)
+}
+
+func TestStatusControllerReconcileGatewayNotFound(t *testing.T) {
+	t.Parallel()
+
+	sc := newTestStatusController(t, istiofake.NewSimpleClientset(), []*v1certmanager.Certificate{
+		newTestCertificate("devops-gateway-123-https", "cert-manager", true),
+	})
+	result, err := sc.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+}
+
+func TestStatusControllerReconcileGatewayAPI(t *testing.T) {
+	t.Parallel()
+
+	gwc := gatewayapifake.NewSimpleClientset()
+	_, err := gwc.GatewayV1beta1().Gateways("devops").Create(context.TODO(), &gatewayapiv1beta1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-123", Namespace: "devops"},
+		Spec: gatewayapiv1beta1.GatewaySpec{
+			Listeners: []gatewayapiv1beta1.Listener{
+				{
+					Name: "https",
+					TLS: &gatewayapiv1beta1.GatewayTLSConfig{
+						CertificateRefs: []gatewayapiv1beta1.SecretObjectReference{{Name: "devops-gateway-123-https"}},
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	cert := newTestCertificate("devops-gateway-123-https", "devops", true)
+	cert.Annotations = map[string]string{v1beta1labels.ManagedGroupAnnotation: gatewayapiv1beta1.GroupName}
+
+	sc := newTestStatusController(t, istiofake.NewSimpleClientset(), []*v1certmanager.Certificate{cert}, WithGatewayAPIClient(gwc))
+	_, err = sc.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	gateway, err := gwc.GatewayV1beta1().Gateways("devops").Get(context.TODO(), "gateway-123", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	servers := decodeStatus(t, gateway.Annotations)
+	assert.Len(t, servers, 1)
+	assert.True(t, servers["https"].Ready)
+}
+
+func TestStatusControllerReconcileSharedCertificate(t *testing.T) {
+	t.Parallel()
+
+	shared := newTestCertificate("devops-gateway-123-https", "cert-manager", true)
+	shared.Labels[v1beta1labels.ManagedLabel] = "other.devops"
+	shared.Annotations = map[string]string{v1beta1labels.ReferencedByAnnotation: "gateway-123.devops,other.devops"}
+
+	unreferenced := newTestCertificate("devops-gateway-123-https-alt", "cert-manager", true)
+	unreferenced.Labels[v1beta1labels.ManagedLabel] = "other.devops"
+
+	istioClient := newTestIstioClient(t, newTestGateway(nil))
+	sc := newTestStatusController(t, istioClient, []*v1certmanager.Certificate{shared, unreferenced})
+	_, err := sc.Reconcile(context.TODO(), reconcileRequest())
+	assert.NoError(t, err)
+
+	gateway, err := istioClient.NetworkingV1().Gateways("devops").Get(context.TODO(), "gateway-123", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	servers := decodeStatus(t, gateway.Annotations)
+	assert.Len(t, servers, 1)
+	assert.True(t, servers["https"].Ready)
+
+	assert.Len(t, gatewayForCertificate(context.TODO(), shared), 2)
+}
+
+func TestGatewayForCertificate(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		[]reconcile.Request{reconcileRequest()},
+		gatewayForCertificate(context.TODO(), newTestCertificate("devops-gateway-123-https", "cert-manager", true)))
+
+	assert.Nil(t, gatewayForCertificate(context.TODO(), &v1certmanager.Certificate{}))
+}
+
+func TestIndexByManagingGateway(t *testing.T) {
+	t.Parallel()
+
+	cert := newTestCertificate("devops-gateway-123-https", "cert-manager", true)
+	keys, err := indexByManagingGateway(cert)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{v1beta1labels.ManagedLabelValue("gateway-123", "devops")}, keys)
+
+	cert.Labels[v1beta1labels.ManagedLabel] = v1beta1labels.ManagedLabelValue("gateway-123", "devops")
+	cert.Annotations = v1beta1labels.ManagedAnnotations("gateway-123", "devops")
+	cert.Annotations[v1beta1labels.ReferencedByAnnotation] = "gateway-123.devops,other.devops"
+	keys, err = indexByManagingGateway(cert)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{v1beta1labels.ManagedLabelValue("gateway-123", "devops"), v1beta1labels.ManagedLabelValue("other", "devops")}, keys)
+
+	keys, err = indexByManagingGateway(&v1certmanager.Certificate{})
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestStatusPatch(t *testing.T) {
+	t.Parallel()
+
+	servers := map[string]ServerStatus{"https": {Certificate: "cert", Ready: true, Issuer: "ClusterIssuer/default"}}
+	encoded := `{"https":{"certificate":"cert","ready":true,"issuer":"ClusterIssuer/default"}}`
+
+	patch, err := statusPatch(nil, servers)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"metadata":{"annotations":{"`+v1beta1labels.StatusAnnotation+`":`+mustQuote(encoded)+`}}}`, string(patch))
+
+	patch, err = statusPatch(map[string]string{v1beta1labels.StatusAnnotation: encoded}, servers)
+	assert.NoError(t, err)
+	assert.Nil(t, patch)
+
+	patch, err = statusPatch(nil, map[string]ServerStatus{})
+	assert.NoError(t, err)
+	assert.Nil(t, patch)
+
+	patch, err = statusPatch(map[string]string{v1beta1labels.StatusAnnotation: encoded}, map[string]ServerStatus{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"metadata":{"annotations":{"`+v1beta1labels.StatusAnnotation+`":null}}}`, string(patch))
+}
+
+func mustQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
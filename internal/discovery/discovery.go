@@ -0,0 +1,53 @@
+package discovery
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sdiscovery "k8s.io/client-go/discovery"
+)
+
+// ServedVersions returns the versions of an API group served by the cluster, preferred version first.
+func ServedVersions(dc k8sdiscovery.DiscoveryInterface, group string) ([]string, error) {
+	groups, err := dc.ServerGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, g := range groups.Groups {
+		if g.Name != group {
+			continue
+		}
+
+		if g.PreferredVersion.Version != "" {
+			versions = append(versions, g.PreferredVersion.Version)
+		}
+
+		for _, v := range g.Versions {
+			if v.Version != g.PreferredVersion.Version {
+				versions = append(versions, v.Version)
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+// ServesResource reports whether the cluster serves a resource in the given group version.
+func ServesResource(dc k8sdiscovery.DiscoveryInterface, gv schema.GroupVersion, resource string) (bool, error) {
+	resources, err := dc.ServerResourcesForGroupVersion(gv.String())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, r := range resources.APIResources {
+		if r.Name == resource {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,73 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newFakeDiscovery(resources ...*metav1.APIResourceList) *fakediscovery.FakeDiscovery {
+	return &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{Resources: resources}}
+}
+
+func TestServedVersions(t *testing.T) {
+	t.Parallel()
+
+	dc := newFakeDiscovery(
+		&metav1.APIResourceList{GroupVersion: "networking.istio.io/v1", APIResources: []metav1.APIResource{{Name: "gateways"}}},
+		&metav1.APIResourceList{GroupVersion: "networking.istio.io/v1beta1", APIResources: []metav1.APIResource{{Name: "gateways"}}},
+		&metav1.APIResourceList{GroupVersion: "cert-manager.io/v1", APIResources: []metav1.APIResource{{Name: "certificates"}}},
+	)
+
+	versions, err := ServedVersions(dc, "networking.istio.io")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"v1", "v1beta1"}, versions)
+
+	versions, err = ServedVersions(dc, "gateway.networking.k8s.io")
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestServesResource(t *testing.T) {
+	t.Parallel()
+
+	dc := newFakeDiscovery(
+		&metav1.APIResourceList{GroupVersion: "networking.istio.io/v1", APIResources: []metav1.APIResource{{Name: "gateways"}, {Name: "virtualservices"}}},
+	)
+
+	tests := []struct {
+		description string
+		gv          schema.GroupVersion
+		resource    string
+		want        bool
+	}{
+		{
+			description: "served resource",
+			gv:          schema.GroupVersion{Group: "networking.istio.io", Version: "v1"},
+			resource:    "gateways",
+			want:        true,
+		},
+		{
+			description: "resource not served in group version",
+			gv:          schema.GroupVersion{Group: "networking.istio.io", Version: "v1"},
+			resource:    "sidecars",
+			want:        false,
+		},
+		{
+			description: "group version not served",
+			gv:          schema.GroupVersion{Group: "networking.istio.io", Version: "v1beta1"},
+			resource:    "gateways",
+			want:        false,
+		},
+	}
+
+	for _, test := range tests {
+		ok, err := ServesResource(dc, test.gv, test.resource)
+		assert.NoError(t, err, test.description)
+		assert.Equal(t, test.want, ok, test.description)
+	}
+}
@@ -2,9 +2,11 @@ package prometheus
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
@@ -13,10 +15,25 @@ var (
 		Name: "managed_certificates_count",
 		Help: "Count of controller managed certificates",
 	})
+	retainedCertificatesCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "retained_certificates_count",
+		Help: "Count of unused managed certificates kept from garbage collection by the retain annotation",
+	})
+	orphanedCertificatesCount = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orphaned_certificates_count",
+		Help: "Count of managed certificates no gateway referenced during the last garbage collection sweep",
+	})
+	certificateAdoptionsRefused = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certificate_adoptions_refused_total",
+		Help: "Count of existing unmanaged certificates left unchanged due to the adoption policy, each certificate is counted once",
+	})
+
+	// refusedCertificates holds the UIDs of the certificates counted in certificateAdoptionsRefused
+	refusedCertificates sync.Map
 )
 
 func init() {
-	metrics.Registry.MustRegister(managedCertificatesCount)
+	metrics.Registry.MustRegister(managedCertificatesCount, retainedCertificatesCount, orphanedCertificatesCount, certificateAdoptionsRefused)
 }
 
 func Handler() http.Handler {
@@ -28,3 +45,19 @@ func Handler() http.Handler {
 func UpdateManagedCertificatesCount(count int) {
 	managedCertificatesCount.Set(float64(count))
 }
+
+func UpdateRetainedCertificatesCount(count int) {
+	retainedCertificatesCount.Set(float64(count))
+}
+
+func UpdateOrphanedCertificatesCount(count int) {
+	orphanedCertificatesCount.Set(float64(count))
+}
+
+// IncrementCertificateAdoptionsRefused counts a certificate whose adoption was refused, the first time it is refused.
+// Later reconciles refusing the same certificate are not counted again.
+func IncrementCertificateAdoptionsRefused(uid types.UID) {
+	if _, seen := refusedCertificates.LoadOrStore(uid, struct{}{}); !seen {
+		certificateAdoptionsRefused.Inc()
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func TestMetrics(t *testing.T) {
@@ -19,4 +21,19 @@ func TestMetrics(t *testing.T) {
 	Handler().ServeHTTP(rr, req)
 	body := rr.Body.String()
 	assert.Contains(t, body, `managed_certificates_count`)
+	assert.Contains(t, body, `retained_certificates_count`)
+	assert.Contains(t, body, `orphaned_certificates_count`)
+	assert.Contains(t, body, `certificate_adoptions_refused_total`)
+}
+
+func TestIncrementCertificateAdoptionsRefused(t *testing.T) {
+	t.Parallel()
+
+	before := testutil.ToFloat64(certificateAdoptionsRefused)
+	IncrementCertificateAdoptionsRefused(types.UID("refused"))
+	IncrementCertificateAdoptionsRefused(types.UID("refused"))
+	assert.Equal(t, before+1, testutil.ToFloat64(certificateAdoptionsRefused))
+
+	IncrementCertificateAdoptionsRefused(types.UID("other"))
+	assert.Equal(t, before+2, testutil.ToFloat64(certificateAdoptionsRefused))
 }
@@ -0,0 +1,65 @@
+package workload
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+)
+
+// NamespaceResolver resolves an Istio Gateway selector to the namespace of the gateway workload.  Istio SDS
+// only reads credentialName Secrets from the namespace of the gateway workload, so Certificates must be
+// created there.
+type NamespaceResolver struct {
+	pods     corev1listers.PodLister
+	services corev1listers.ServiceLister
+}
+
+func NewNamespaceResolver(pods corev1listers.PodLister, services corev1listers.ServiceLister) *NamespaceResolver {
+	return &NamespaceResolver{
+		pods:     pods,
+		services: services,
+	}
+}
+
+// Namespace returns the namespace of the Pods matching the selector, or of the Services labeled with the
+// selector when no Pods match, e.g. while the gateway deployment is scaled to zero.  An error is returned when
+// nothing matches or the matches span more than one namespace.
+func (r *NamespaceResolver) Namespace(selector map[string]string) (string, error) {
+	if len(selector) == 0 {
+		return "", fmt.Errorf("gateway selector is empty")
+	}
+
+	s := labels.SelectorFromSet(selector)
+
+	pods, err := r.pods.List(s)
+	if err != nil {
+		return "", err
+	}
+
+	namespaces := sets.New[string]()
+	for _, pod := range pods {
+		namespaces.Insert(pod.Namespace)
+	}
+
+	if namespaces.Len() == 0 {
+		services, err := r.services.List(s)
+		if err != nil {
+			return "", err
+		}
+
+		for _, service := range services {
+			namespaces.Insert(service.Namespace)
+		}
+	}
+
+	switch namespaces.Len() {
+	case 0:
+		return "", fmt.Errorf("no gateway Pods or Services match selector %q", s)
+	case 1:
+		return namespaces.UnsortedList()[0], nil
+	default:
+		return "", fmt.Errorf("gateway selector %q matches workloads in more than one namespace: %v", s, sets.List(namespaces))
+	}
+}
@@ -0,0 +1,94 @@
+package workload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newTestResolver(t *testing.T, objs ...metav1.Object) *NamespaceResolver {
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	services := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.Pod:
+			assert.NoError(t, pods.Add(o))
+		case *corev1.Service:
+			assert.NoError(t, services.Add(o))
+		}
+	}
+
+	return NewNamespaceResolver(corev1listers.NewPodLister(pods), corev1listers.NewServiceLister(services))
+}
+
+func TestNamespaceResolverNamespace(t *testing.T) {
+	t.Parallel()
+
+	ingress := map[string]string{"istio": "ingressgateway"}
+
+	tests := []struct {
+		description string
+		objs        []metav1.Object
+		selector    map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{
+			description: "pods",
+			objs: []metav1.Object{
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway-1", Namespace: "istio-ingress", Labels: ingress}},
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway-2", Namespace: "istio-ingress", Labels: ingress}},
+				&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway", Namespace: "other", Labels: ingress}},
+			},
+			selector: ingress,
+			want:     "istio-ingress",
+		},
+		{
+			description: "services when no pods match",
+			objs: []metav1.Object{
+				&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway", Namespace: "istio-ingress", Labels: ingress}},
+			},
+			selector: ingress,
+			want:     "istio-ingress",
+		},
+		{
+			description: "selector must match all labels",
+			objs: []metav1.Object{
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway-1", Namespace: "istio-ingress", Labels: ingress}},
+			},
+			selector: map[string]string{"istio": "ingressgateway", "app": "internal"},
+			wantErr:  true,
+		},
+		{
+			description: "more than one namespace",
+			objs: []metav1.Object{
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway-1", Namespace: "istio-ingress", Labels: ingress}},
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway-1", Namespace: "istio-system", Labels: ingress}},
+			},
+			selector: ingress,
+			wantErr:  true,
+		},
+		{
+			description: "empty selector",
+			objs: []metav1.Object{
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "ingressgateway-1", Namespace: "istio-ingress", Labels: ingress}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		namespace, err := newTestResolver(t, test.objs...).Namespace(test.selector)
+		assert.Equal(t, test.want, namespace, test.description)
+		if test.wantErr {
+			assert.Error(t, err, test.description)
+		} else {
+			assert.NoError(t, err, test.description)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package challengesolver
 
+import "k8s.io/client-go/tools/record"
+
 type OptionsFunc func(cs *ChallengeSolver)
 
 func WithDryRun(dryrun bool) OptionsFunc {
@@ -7,3 +9,10 @@ func WithDryRun(dryrun bool) OptionsFunc {
 		cs.dryRun = dryrun
 	}
 }
+
+// WithEventRecorder records Kubernetes Events on Challenges for solver VirtualService changes and failures.
+func WithEventRecorder(r record.EventRecorder) OptionsFunc {
+	return func(cs *ChallengeSolver) {
+		cs.recorder = r
+	}
+}
@@ -13,19 +13,22 @@ import (
 
 	"github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/cache"
 
-	apinetv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	apinetv1 "istio.io/client-go/pkg/apis/networking/v1"
 	netapplymetav1 "istio.io/client-go/pkg/applyconfiguration/meta/v1"
-	netapplyv1beta1 "istio.io/client-go/pkg/applyconfiguration/networking/v1beta1"
-	networkingv1beta1Client "istio.io/client-go/pkg/clientset/versioned/typed/networking/v1beta1"
+	netapplyv1 "istio.io/client-go/pkg/applyconfiguration/networking/v1"
+	networkingv1Client "istio.io/client-go/pkg/clientset/versioned/typed/networking/v1"
 
-	istiov1beta1 "istio.io/api/networking/v1beta1"
+	istiov1 "istio.io/api/networking/v1"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -37,14 +40,23 @@ import (
 
 type ChallengeSolver struct {
 	coreClient        corev1listers.ServiceLister
-	networkingClient  networkingv1beta1Client.NetworkingV1beta1Interface
+	networkingClient  networkingv1Client.NetworkingV1Interface
 	acmeClient        acmev1Client.AcmeV1Interface
 	certmanagerClient certmanagerversionedclient.Interface
 	glc               *cache.GatewayLookupCache
 	dryRun            bool
+	recorder          record.EventRecorder
 }
 
-func NewChallengeSolver(cc corev1listers.ServiceLister, nc networkingv1beta1Client.NetworkingV1beta1Interface, cmc certmanagerversionedclient.Interface, glc *cache.GatewayLookupCache, opts ...OptionsFunc) *ChallengeSolver {
+const (
+	reasonGatewayNotFound           = "GatewayNotFound"
+	reasonSolverServiceNotFound     = "SolverServiceNotFound"
+	reasonSolverServiceInvalid      = "SolverServiceInvalid"
+	reasonVirtualServiceApplied     = "VirtualServiceApplied"
+	reasonVirtualServiceApplyFailed = "VirtualServiceApplyFailed"
+)
+
+func NewChallengeSolver(cc corev1listers.ServiceLister, nc networkingv1Client.NetworkingV1Interface, cmc certmanagerversionedclient.Interface, glc *cache.GatewayLookupCache, opts ...OptionsFunc) *ChallengeSolver {
 
 	cs := &ChallengeSolver{
 		coreClient:        cc,
@@ -120,7 +132,7 @@ func (cs *ChallengeSolver) Reconcile(ctx context.Context, req reconcile.Request)
 	return reconcile.Result{}, nil
 }
 
-func (cs *ChallengeSolver) Solve(ctx context.Context, challenge *acmev1.Challenge) (*apinetv1beta1.VirtualService, error) {
+func (cs *ChallengeSolver) Solve(ctx context.Context, challenge *acmev1.Challenge) (*apinetv1.VirtualService, error) {
 	log := log.FromContext(ctx)
 	log.V(1).Info("Debug")
 
@@ -135,6 +147,7 @@ func (cs *ChallengeSolver) Solve(ctx context.Context, challenge *acmev1.Challeng
 	if !ok {
 		// requeue the request to wait for the lookup cache to populate
 		// probably needs backoff
+		cs.recordEvent(challenge, corev1.EventTypeWarning, reasonGatewayNotFound, "No Gateway found for host %s, requeued", challenge.Spec.DNSName)
 		return nil, fmt.Errorf("host %s: gateway not found", challenge.Spec.DNSName)
 	}
 	log.V(1).Info(fmt.Sprintf("Debug: gateway found %s", namespacedGateway))
@@ -149,12 +162,14 @@ func (cs *ChallengeSolver) Solve(ctx context.Context, challenge *acmev1.Challeng
 
 	if len(serviceList) == 0 {
 		// requeue the request to wait for the service to appear in the api
+		cs.recordEvent(challenge, corev1.EventTypeWarning, reasonSolverServiceNotFound, "No solver Service found for host %s, requeued", challenge.Spec.DNSName)
 		return nil, fmt.Errorf("no service matched selector: %s", fmt.Sprintf("%s=%s,%s=%s", acmev1.DomainLabelKey, httpDomainHash, acmev1.TokenLabelKey, tokenHash))
 	}
 	svc := serviceList[0]
 
 	if len(svc.Spec.Ports) == 0 {
 		// this is probably unrecoverable
+		cs.recordEvent(challenge, corev1.EventTypeWarning, reasonSolverServiceInvalid, "Solver Service %s is missing a port definition", svc.Name)
 		return nil, fmt.Errorf("service: %s, missing port definition", svc.Name)
 	}
 
@@ -176,7 +191,23 @@ func (cs *ChallengeSolver) Solve(ctx context.Context, challenge *acmev1.Challeng
 		return nil, nil
 	}
 
-	return cs.networkingClient.VirtualServices(challenge.Namespace).Apply(ctx, vsApply, metav1.ApplyOptions{Force: true, FieldManager: "challengesolver"})
+	vs, err := cs.networkingClient.VirtualServices(challenge.Namespace).Apply(ctx, vsApply, metav1.ApplyOptions{Force: true, FieldManager: "challengesolver"})
+	if err != nil {
+		cs.recordEvent(challenge, corev1.EventTypeWarning, reasonVirtualServiceApplyFailed, "Failed to apply VirtualService %s/%s for Gateway %s: %s", *vsApply.Namespace, *vsApply.Name, namespacedGateway, err)
+		return nil, err
+	}
+
+	cs.recordEvent(challenge, corev1.EventTypeNormal, reasonVirtualServiceApplied, "Applied VirtualService %s/%s for Gateway %s", *vsApply.Namespace, *vsApply.Name, namespacedGateway)
+	return vs, nil
+}
+
+// recordEvent is a no-op without an event recorder or in dry-run mode.
+func (cs *ChallengeSolver) recordEvent(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if cs.recorder == nil || cs.dryRun {
+		return
+	}
+
+	cs.recorder.Eventf(object, eventtype, reason, messageFmt, args...)
 }
 
 func (cs *ChallengeSolver) Hash(in string) string {
@@ -194,33 +225,33 @@ type ChallengeMeta struct {
 	Gateway   string
 }
 
-func VirtualServiceApplyFromChallengeMeta(cm ChallengeMeta) *netapplyv1beta1.VirtualServiceApplyConfiguration {
+func VirtualServiceApplyFromChallengeMeta(cm ChallengeMeta) *netapplyv1.VirtualServiceApplyConfiguration {
 
-	vsAPIVersion := apinetv1beta1.SchemeGroupVersion.String()
+	vsAPIVersion := apinetv1.SchemeGroupVersion.String()
 	vsKind := "VirtualService"
 
-	vsApply := netapplyv1beta1.VirtualServiceApplyConfiguration{
+	vsApply := netapplyv1.VirtualServiceApplyConfiguration{
 		ObjectMetaApplyConfiguration: &netapplymetav1.ObjectMetaApplyConfiguration{},
-		Spec: &istiov1beta1.VirtualService{
+		Spec: &istiov1.VirtualService{
 			Hosts:    []string{cm.DNSName},
 			Gateways: []string{cm.Gateway},
-			Http: []*istiov1beta1.HTTPRoute{
+			Http: []*istiov1.HTTPRoute{
 				{
 					Name: "solver",
-					Match: []*istiov1beta1.HTTPMatchRequest{
+					Match: []*istiov1.HTTPMatchRequest{
 						{
-							Uri: &istiov1beta1.StringMatch{
-								MatchType: &istiov1beta1.StringMatch_Exact{
+							Uri: &istiov1.StringMatch{
+								MatchType: &istiov1.StringMatch_Exact{
 									Exact: fmt.Sprintf("/.well-known/acme-challenge/%s", cm.Token),
 								},
 							},
 						},
 					},
-					Route: []*istiov1beta1.HTTPRouteDestination{
+					Route: []*istiov1.HTTPRouteDestination{
 						{
-							Destination: &istiov1beta1.Destination{
+							Destination: &istiov1.Destination{
 								Host: cm.Service,
-								Port: &istiov1beta1.PortSelector{
+								Port: &istiov1.PortSelector{
 									Number: uint32(cm.Port),
 								},
 							},
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"hash/adler32"
+	"strings"
 	"testing"
 
 	acmev1 "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
@@ -16,15 +17,16 @@ import (
 
 	certmanagerfake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/fake"
 	acmefake "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned/typed/acme/v1/fake"
-	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	networkingv1 "istio.io/client-go/pkg/apis/networking/v1"
 	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
-	networkingv1beta1fake "istio.io/client-go/pkg/clientset/versioned/typed/networking/v1beta1/fake"
+	networkingv1fake "istio.io/client-go/pkg/clientset/versioned/typed/networking/v1/fake"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 )
 
 type testHelper struct {
@@ -34,8 +36,8 @@ type testHelper struct {
 	glc *cache.GatewayLookupCache
 }
 
-func (th *testHelper) newTestSolver() *challengesolver.ChallengeSolver {
-	return challengesolver.NewChallengeSolver(th.scs, th.ics.NetworkingV1beta1(), th.ccs, th.glc)
+func (th *testHelper) newTestSolver(opts ...challengesolver.OptionsFunc) *challengesolver.ChallengeSolver {
+	return challengesolver.NewChallengeSolver(th.scs, th.ics.NetworkingV1(), th.ccs, th.glc, opts...)
 }
 
 func TestChallengeSolver(t *testing.T) {
@@ -43,11 +45,12 @@ func TestChallengeSolver(t *testing.T) {
 		name           string
 		challenge      *acmev1.Challenge
 		service        *corev1.Service
-		virtualService *networkingv1beta1.VirtualService
+		virtualService *networkingv1.VirtualService
 		gatewayName    string
 		pass           bool
 		validateVS     bool
 		noRequeue      bool
+		event          string
 	}{
 		{
 			name:      "no challenge",
@@ -58,17 +61,20 @@ func TestChallengeSolver(t *testing.T) {
 			name:      "No Gateway",
 			challenge: getChallenge("noservice", "example", "noservice.com"),
 			pass:      false,
+			event:     "Warning GatewayNotFound",
 		},
 		{
 			name:      "No Gateway",
 			challenge: getChallenge("noservice", "example", "noservice.com"),
 			pass:      false,
+			event:     "Warning GatewayNotFound",
 		},
 		{
 			name:        "No Service",
 			challenge:   getChallenge("noservice", "example", "noservice.com"),
 			gatewayName: "gateway",
 			pass:        false,
+			event:       "Warning SolverServiceNotFound",
 		},
 		{
 			name:        "No Service Port",
@@ -76,6 +82,7 @@ func TestChallengeSolver(t *testing.T) {
 			gatewayName: "gateway",
 			service:     getService("noportservice", "example", "noportservice.com", 0),
 			pass:        false,
+			event:       "Warning SolverServiceInvalid",
 		},
 		{
 			name:        "Service",
@@ -85,6 +92,7 @@ func TestChallengeSolver(t *testing.T) {
 			pass:        true,
 			validateVS:  true,
 			noRequeue:   true,
+			event:       "Normal VirtualServiceApplied",
 		},
 	} {
 		th := testHelper{
@@ -105,10 +113,10 @@ func TestChallengeSolver(t *testing.T) {
 				th.glc.Add(fmt.Sprintf("%s/%s", test.challenge.Namespace, test.gatewayName), test.challenge.Spec.DNSName)
 			}
 
-			vs := networkingv1beta1.VirtualService{}
+			vs := networkingv1.VirtualService{}
 			vs.Name = test.challenge.Name
 			vs.Namespace = test.challenge.Namespace
-			th.ics.NetworkingV1beta1().(*networkingv1beta1fake.FakeNetworkingV1beta1).PrependReactor(
+			th.ics.NetworkingV1().(*networkingv1fake.FakeNetworkingV1).PrependReactor(
 				"patch",
 				"virtualservices",
 				func(action k8stesting.Action) (bool, runtime.Object, error) {
@@ -119,10 +127,18 @@ func TestChallengeSolver(t *testing.T) {
 
 		}
 
-		cs := th.newTestSolver()
+		recorder := record.NewFakeRecorder(10)
+		cs := th.newTestSolver(challengesolver.WithEventRecorder(recorder))
 
 		out, err := cs.Solve(context.Background(), test.challenge)
 
+		if test.event != "" {
+			assert.Len(t, recorder.Events, 1, test.name)
+			assert.True(t, strings.HasPrefix(<-recorder.Events, test.event), test.name)
+		} else {
+			assert.Len(t, recorder.Events, 0, test.name)
+		}
+
 		if test.pass {
 			assert.NoError(t, err, test.name)
 
@@ -1,6 +1,8 @@
 package labels
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
@@ -15,9 +17,38 @@ type Label string
 var (
 	InjectSimpleCredentialNameLabel     = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-inject-simple-credential-name")
 	IssuerAnnotation                    = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-issuer")
+	IssuerKindAnnotation                = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-issuer-kind")
+	IssuerGroupAnnotation               = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-issuer-group")
 	ManagedLabel                        = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-managed")
 	IssueTemporaryCertificateAnnotation = fmt.Sprintf("%s/%s", version.String(), IssueTemporaryCertificate)
 	HTTPSolverAnnotation                = fmt.Sprintf("%s/%s", version.String(), HTTP01)
+	ManagedGroupAnnotation              = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-managed-group")
+	ManagedNameAnnotation               = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-managed-name")
+	ManagedNamespaceAnnotation          = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-managed-namespace")
+	StatusAnnotation                    = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-status")
+	ClientCAAnnotation                  = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-client-ca")
+	GranularityAnnotation               = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-granularity")
+	ReferencedByAnnotation              = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-referenced-by")
+	PendingDeletionAnnotation           = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-pending-deletion")
+	RetainAnnotation                    = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-retain")
+	UnmanagedServersAnnotation          = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-unmanaged-servers")
+
+	// Namespace issuer policy, see docs/api/v1beta1.md
+	AllowedIssuersAnnotation = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-allowed-issuers")
+
+	// Certificate sharding, see docs/controllers/gateway.md
+	ShardCountAnnotation = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-shard-count")
+	ShardOfAnnotation    = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-shard-of")
+	ShardIndexAnnotation = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-shard-index")
+
+	// Certificate spec overrides, see docs/api/v1beta1.md for the accepted values
+	DurationAnnotation                 = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-duration")
+	RenewBeforeAnnotation              = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-renew-before")
+	PrivateKeyAlgorithmAnnotation      = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-private-key-algorithm")
+	PrivateKeySizeAnnotation           = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-private-key-size")
+	PrivateKeyRotationPolicyAnnotation = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-private-key-rotation-policy")
+	UsagesAnnotation                   = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-usages")
+	SubjectAnnotation                  = fmt.Sprintf("%s/%s", version.String(), "istio-cert-controller-subject")
 )
 
 const (
@@ -38,6 +69,9 @@ const (
 
 	//HTTP01
 	HTTP01 = "http01"
+
+	// managedLabelHashLength is the number of hex characters of the hashed ManagedLabel value
+	managedLabelHashLength = 40
 )
 
 func InjectSimpleCredentialNameLabelSelector() string {
@@ -51,11 +85,89 @@ func ManagedLabelSelector() string {
 	return apilabels.NewSelector().Add(*managedReq).String()
 }
 
+// ManagedLabelValue returns the ManagedLabel value of a resource managed for a Gateway.  Gateway names may be
+// longer than a label value and contain dots, so the value is a hash of the namespace and name and the Gateway is
+// recorded in the ManagedNameAnnotation and ManagedNamespaceAnnotation, see ManagedAnnotations.
+func ManagedLabelValue(gateway, namespace string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s/%s", namespace, gateway)))
+	return hex.EncodeToString(sum[:])[:managedLabelHashLength]
+}
+
+// ManagedAnnotations returns the annotations recording the Gateway a resource is managed for.
+func ManagedAnnotations(gateway, namespace string) map[string]string {
+	return map[string]string{
+		ManagedNameAnnotation:      gateway,
+		ManagedNamespaceAnnotation: namespace,
+	}
+}
+
+// ManagedGateway returns the name and namespace of the Gateway a resource is managed for, read from the
+// ManagedAnnotations or, for resources written by earlier releases, the "<name>.<namespace>" ManagedLabel value.
+// Empty strings are returned when neither is set.
+func ManagedGateway(labels, annotations map[string]string) (gateway string, namespace string) {
+	if gateway, namespace = annotations[ManagedNameAnnotation], annotations[ManagedNamespaceAnnotation]; gateway != "" && namespace != "" {
+		return gateway, namespace
+	}
+
+	return ParseManagedLabel(labels[ManagedLabel])
+}
+
+// GatewayReference returns the "<name>.<namespace>" reference to a Gateway used by the ReferencedByAnnotation
+// and, before the ManagedAnnotations, the ManagedLabel value.
+func GatewayReference(gateway, namespace string) string {
+	return fmt.Sprintf("%s.%s", gateway, namespace)
+}
+
+// ParseManagedLabel parses a "<name>.<namespace>" Gateway reference.  Gateway names may contain dots but
+// namespaces may not, so the reference is split on its last dot.  Hashed ManagedLabel values do not contain a
+// dot and return empty strings.
 func ParseManagedLabel(in string) (gateway string, namespace string) {
-	s := strings.Split(in, ".")
-	if len(s) < 2 {
+	i := strings.LastIndex(in, ".")
+	if i < 0 {
 		return "", ""
 	}
 
-	return s[0], s[1]
+	return in[:i], in[i+1:]
+}
+
+// ParseReferencedBy returns the Gateway references listed in a ReferencedByAnnotation.
+func ParseReferencedBy(in string) []string {
+	return splitList(in)
+}
+
+// ParseUnmanagedServers returns the port names and hosts listed in an UnmanagedServersAnnotation.
+func ParseUnmanagedServers(in string) []string {
+	return splitList(in)
+}
+
+// ParseAllowedIssuers returns the "<name>", "<kind>/<name>" and "<kind>.<group>/<name>" issuers listed in an
+// AllowedIssuersAnnotation.
+func ParseAllowedIssuers(in string) []string {
+	return splitList(in)
+}
+
+// splitList splits a comma separated annotation value, dropping empty entries.
+func splitList(in string) []string {
+	entries := []string{}
+	for _, e := range strings.Split(in, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+// ManagingGateways returns the references of the Gateways using a managed Certificate, the Gateways referencing
+// a shared Certificate or the Gateway it is managed for.
+func ManagingGateways(labels, annotations map[string]string) []string {
+	if v, ok := annotations[ReferencedByAnnotation]; ok {
+		return ParseReferencedBy(v)
+	}
+
+	if gateway, namespace := ManagedGateway(labels, annotations); gateway != "" {
+		return []string{GatewayReference(gateway, namespace)}
+	}
+
+	return []string{}
 }
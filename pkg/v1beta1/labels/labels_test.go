@@ -1,6 +1,7 @@
 package labels
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,11 +22,144 @@ func TestIssuerAnnotation(t *testing.T) {
 	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-issuer", IssuerAnnotation)
 }
 
+func TestIssuerKindAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-issuer-kind", IssuerKindAnnotation)
+}
+
+func TestIssuerGroupAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-issuer-group", IssuerGroupAnnotation)
+}
+
 func TestManagedLabel(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-managed", ManagedLabel)
 }
 
+func TestManagedGroupAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-managed-group", ManagedGroupAnnotation)
+}
+
+func TestStatusAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-status", StatusAnnotation)
+}
+
+func TestClientCAAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-client-ca", ClientCAAnnotation)
+}
+
+func TestGranularityAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-granularity", GranularityAnnotation)
+}
+
+func TestReferencedByAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-referenced-by", ReferencedByAnnotation)
+}
+
+func TestUnmanagedServersAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-unmanaged-servers", UnmanagedServersAnnotation)
+	assert.Equal(t, []string{"https-ev", "shop.example.com"}, ParseUnmanagedServers(" https-ev,, shop.example.com "))
+	assert.Equal(t, []string{}, ParseUnmanagedServers(""))
+}
+
+func TestAllowedIssuersAnnotation(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-allowed-issuers", AllowedIssuersAnnotation)
+	assert.Equal(t, []string{"letsencrypt", "Issuer/team-ca", "StepIssuer.certmanager.step.sm/step-ca"}, ParseAllowedIssuers("letsencrypt, Issuer/team-ca,, StepIssuer.certmanager.step.sm/step-ca"))
+}
+
+func TestManagedAnnotations(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-managed-name", ManagedNameAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-managed-namespace", ManagedNamespaceAnnotation)
+}
+
+func TestManagedLabelValue(t *testing.T) {
+	t.Parallel()
+
+	v := ManagedLabelValue("api.example.com-gw", "test-ns")
+	assert.Regexp(t, "^[0-9a-f]{40}$", v)
+	assert.Equal(t, v, ManagedLabelValue("api.example.com-gw", "test-ns"))
+	assert.NotEqual(t, v, ManagedLabelValue("api.example.com-gw", "other-ns"))
+
+	// names longer than a label value are hashed to a valid label value
+	assert.Len(t, ManagedLabelValue(strings.Repeat("a", 253), "test-ns"), 40)
+}
+
+func TestManagedGateway(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description   string
+		labels        map[string]string
+		annotations   map[string]string
+		wantGateway   string
+		wantNamespace string
+	}{
+		{
+			description:   "annotations",
+			labels:        map[string]string{ManagedLabel: ManagedLabelValue("api.example.com-gw", "test-ns")},
+			annotations:   ManagedAnnotations("api.example.com-gw", "test-ns"),
+			wantGateway:   "api.example.com-gw",
+			wantNamespace: "test-ns",
+		},
+		{
+			description:   "legacy label",
+			labels:        map[string]string{ManagedLabel: "api.example.com-gw.test-ns"},
+			wantGateway:   "api.example.com-gw",
+			wantNamespace: "test-ns",
+		},
+		{
+			description: "hashed label without annotations",
+			labels:      map[string]string{ManagedLabel: ManagedLabelValue("api.example.com-gw", "test-ns")},
+		},
+		{
+			description: "unmanaged",
+		},
+	}
+
+	for _, test := range tests {
+		g, ns := ManagedGateway(test.labels, test.annotations)
+		assert.Equal(t, test.wantGateway, g, test.description)
+		assert.Equal(t, test.wantNamespace, ns, test.description)
+	}
+}
+
+func TestManagingGateways(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{ManagedLabel: "a.default"}
+	assert.Equal(t, []string{"a.default"}, ManagingGateways(labels, nil))
+	assert.Equal(t, []string{"a.default", "b.other"}, ManagingGateways(labels, map[string]string{ReferencedByAnnotation: "a.default, b.other,"}))
+	assert.Equal(t, []string{}, ManagingGateways(labels, map[string]string{ReferencedByAnnotation: ""}))
+	assert.Equal(t, []string{}, ManagingGateways(nil, nil))
+}
+
+func TestShardAnnotations(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-shard-count", ShardCountAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-shard-of", ShardOfAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-shard-index", ShardIndexAnnotation)
+}
+
+func TestCertificateSpecAnnotations(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-duration", DurationAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-renew-before", RenewBeforeAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-private-key-algorithm", PrivateKeyAlgorithmAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-private-key-size", PrivateKeySizeAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-private-key-rotation-policy", PrivateKeyRotationPolicyAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-usages", UsagesAnnotation)
+	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-subject", SubjectAnnotation)
+}
+
 func TestManagedLabelSelector(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "v1beta1.kanopy-platform.github.io/istio-cert-controller-managed", ManagedLabelSelector())
@@ -59,6 +193,16 @@ func TestParseManagedLabel(t *testing.T) {
 			wantGateway:   "test-gateway",
 			wantNamespace: "test-ns",
 		},
+		{
+			input:         "api.example.com-gw.test-ns",
+			wantGateway:   "api.example.com-gw",
+			wantNamespace: "test-ns",
+		},
+		{
+			input:         ManagedLabelValue("test-gateway", "test-ns"),
+			wantGateway:   "",
+			wantNamespace: "",
+		},
 	}
 
 	for _, test := range tests {
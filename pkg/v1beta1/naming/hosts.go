@@ -0,0 +1,97 @@
+package naming
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Hosts are the Gateway hosts normalized into Certificate dnsNames and ipAddresses, along with the hosts that
+// cannot be issued and the reason they were skipped.
+type Hosts struct {
+	DNSNames    []string
+	IPAddresses []string
+	Skipped     []string
+}
+
+// Len returns the number of names the hosts add to a Certificate.
+func (h Hosts) Len() int {
+	return len(h.DNSNames) + len(h.IPAddresses)
+}
+
+// NormalizeHosts converts Istio server hosts or Gateway API listener hostnames into Certificate names.  The
+// Istio namespace prefix ("ns/", "./", "*/") is removed, names are lowercased and converted to punycode, IP
+// literals are moved to IPAddresses, duplicates are removed and both lists are sorted.  The bare "*" host, "*."
+// wildcards when the issuer cannot issue them and invalid names are skipped.
+func NormalizeHosts(hosts []string, wildcards bool) Hosts {
+	dnsNames := sets.New[string]()
+	ipAddresses := sets.New[string]()
+	skipped := []string{}
+
+	for _, h := range hosts {
+		host := h
+		if i := strings.Index(host, "/"); i >= 0 {
+			host = host[i+1:]
+		}
+		host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+
+		if host == "" || host == "*" {
+			skipped = append(skipped, fmt.Sprintf("%q: matches any host", h))
+			continue
+		}
+
+		if ip := net.ParseIP(host); ip != nil {
+			ipAddresses.Insert(ip.String())
+			continue
+		}
+
+		prefix := ""
+		if strings.HasPrefix(host, "*.") {
+			if !wildcards {
+				skipped = append(skipped, fmt.Sprintf("%q: the issuer does not support wildcards", h))
+				continue
+			}
+			prefix, host = "*.", strings.TrimPrefix(host, "*.")
+		}
+
+		name, err := idna.Lookup.ToASCII(host)
+		if err == nil && (strings.Contains(name, "*") || strings.Contains(name, "..") || strings.HasPrefix(name, ".")) {
+			err = fmt.Errorf("invalid DNS name")
+		}
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%q: %s", h, err))
+			continue
+		}
+
+		dnsNames.Insert(prefix + name)
+	}
+
+	return Hosts{
+		DNSNames:    sets.List(dnsNames),
+		IPAddresses: sets.List(ipAddresses),
+		Skipped:     skipped,
+	}
+}
+
+// CertificateNameCount returns the number of names the hosts add to a Certificate, assuming the issuer
+// supports wildcards.
+func CertificateNameCount(hosts []string) int {
+	return NormalizeHosts(hosts, true).Len()
+}
+
+// HostCredentialSuffix returns the credentialName suffix of a Certificate for a single host, e.g.
+// "wildcard.example.com" for "*/*.example.com".  False is returned for hosts that cannot be issued.
+func HostCredentialSuffix(host string) (string, bool) {
+	hosts := NormalizeHosts([]string{host}, true)
+	switch {
+	case len(hosts.DNSNames) == 1:
+		return strings.Replace(hosts.DNSNames[0], "*.", "wildcard.", 1), true
+	case len(hosts.IPAddresses) == 1:
+		return strings.ReplaceAll(hosts.IPAddresses[0], ":", "-"), true
+	default:
+		return "", false
+	}
+}
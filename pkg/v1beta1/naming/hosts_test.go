@@ -0,0 +1,96 @@
+package naming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHosts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		hosts       []string
+		wildcards   bool
+		dnsNames    []string
+		ipAddresses []string
+		skipped     int
+	}{
+		{
+			description: "namespace prefixes are removed",
+			hosts:       []string{"default/a.example.com", "./b.example.com", "*/c.example.com", "d.example.com"},
+			dnsNames:    []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"},
+		},
+		{
+			description: "names are lowercased and deduplicated",
+			hosts:       []string{"A.Example.com", "default/a.example.com", "a.example.com."},
+			dnsNames:    []string{"a.example.com"},
+		},
+		{
+			description: "IDNs are converted to punycode",
+			hosts:       []string{"Bücher.example.com"},
+			dnsNames:    []string{"xn--bcher-kva.example.com"},
+		},
+		{
+			description: "bare wildcards are skipped",
+			hosts:       []string{"*", "*/*", "a.example.com"},
+			dnsNames:    []string{"a.example.com"},
+			skipped:     2,
+		},
+		{
+			description: "wildcards are kept when supported",
+			hosts:       []string{"*/*.Example.com"},
+			wildcards:   true,
+			dnsNames:    []string{"*.example.com"},
+		},
+		{
+			description: "wildcards are skipped when not supported",
+			hosts:       []string{"*.example.com", "a.example.com"},
+			dnsNames:    []string{"a.example.com"},
+			skipped:     1,
+		},
+		{
+			description: "IPs are moved to ipAddresses",
+			hosts:       []string{"10.0.0.1", "default/2001:DB8::1", "a.example.com"},
+			dnsNames:    []string{"a.example.com"},
+			ipAddresses: []string{"10.0.0.1", "2001:db8::1"},
+		},
+		{
+			description: "invalid names are skipped",
+			hosts:       []string{"a.*.example.com", "a..example.com", "-a.example.com", "a_b.example.com"},
+			wildcards:   true,
+			dnsNames:    []string{},
+			skipped:     4,
+		},
+	}
+
+	for _, test := range tests {
+		hosts := NormalizeHosts(test.hosts, test.wildcards)
+		if test.ipAddresses == nil {
+			test.ipAddresses = []string{}
+		}
+		assert.Equal(t, test.dnsNames, hosts.DNSNames, test.description)
+		assert.Equal(t, test.ipAddresses, hosts.IPAddresses, test.description)
+		assert.Len(t, hosts.Skipped, test.skipped, test.description)
+	}
+}
+
+func TestHostCredentialSuffix(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"default/A.example.com": "a.example.com",
+		"*/*.example.com":       "wildcard.example.com",
+		"2001:db8::1":           "2001-db8--1",
+	}
+
+	for host, want := range tests {
+		suffix, ok := HostCredentialSuffix(host)
+		assert.True(t, ok, host)
+		assert.Equal(t, want, suffix, host)
+	}
+
+	_, ok := HostCredentialSuffix("*")
+	assert.False(t, ok)
+}
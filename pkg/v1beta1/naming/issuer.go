@@ -0,0 +1,88 @@
+package naming
+
+import (
+	"strings"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultIssuerKind is the kind of the default issuer and of an annotated issuer name without a kind.
+	DefaultIssuerKind = "ClusterIssuer"
+	// DefaultIssuerGroup is the group of cert-manager issuers, used when an issuer has no group.
+	DefaultIssuerGroup = "cert-manager.io"
+)
+
+// ResolveIssuer returns the issuer requested by the Gateway annotations, defaulting to the issuer annotated on
+// the namespace of the Gateway and then to the clusterIssuer.  When the namespace has an AllowedIssuersAnnotation
+// a requested issuer it does not list is replaced by the default, and true is returned.  ns may be nil when the
+// namespace is unknown.
+func ResolveIssuer(clusterIssuer string, ns *corev1.Namespace, gateway metav1.Object) (v1.ObjectReference, bool) {
+	def := v1.ObjectReference{Kind: DefaultIssuerKind, Name: clusterIssuer, Group: DefaultIssuerGroup}
+	if ns != nil {
+		def = AnnotatedIssuer(def, ns.Annotations)
+	}
+
+	requested := AnnotatedIssuer(def, gateway.GetAnnotations())
+	if ns == nil || requested == def {
+		return requested, false
+	}
+
+	allowed, ok := ns.Annotations[v1beta1labels.AllowedIssuersAnnotation]
+	if !ok || IssuerAllowed(requested, v1beta1labels.ParseAllowedIssuers(allowed)) {
+		return requested, false
+	}
+
+	return def, true
+}
+
+// AnnotatedIssuer returns the issuer selected by the IssuerAnnotation, IssuerKindAnnotation and
+// IssuerGroupAnnotation.  An issuer name selects a cert-manager ClusterIssuer unless the kind and group are
+// annotated too, e.g. for external issuers such as step-issuer.  Without a name the kind and group override def.
+func AnnotatedIssuer(def v1.ObjectReference, annotations map[string]string) v1.ObjectReference {
+	ref := def
+	if i, ok := annotations[v1beta1labels.IssuerAnnotation]; ok {
+		ref = v1.ObjectReference{Kind: DefaultIssuerKind, Name: i, Group: DefaultIssuerGroup}
+	}
+
+	if k, ok := annotations[v1beta1labels.IssuerKindAnnotation]; ok && k != "" {
+		ref.Kind = k
+	}
+
+	if g, ok := annotations[v1beta1labels.IssuerGroupAnnotation]; ok && g != "" {
+		ref.Group = g
+	}
+
+	return ref
+}
+
+// IssuerAllowed reports whether the allowlist has the issuer, by "<name>" for any cert-manager kind, by
+// "<kind>/<name>" for a cert-manager kind or by "<kind>.<group>/<name>" for an issuer of another group.
+func IssuerAllowed(ref v1.ObjectReference, allowed []string) bool {
+	group := ref.Group
+	if group == "" {
+		group = DefaultIssuerGroup
+	}
+
+	for _, a := range allowed {
+		kind, name, ok := strings.Cut(a, "/")
+		if !ok {
+			kind, name = "", a
+		}
+
+		entryGroup := DefaultIssuerGroup
+		if k, g, ok := strings.Cut(kind, "."); ok {
+			kind, entryGroup = k, g
+		}
+
+		if name == ref.Name && strings.EqualFold(entryGroup, group) && (kind == "" || strings.EqualFold(kind, ref.Kind)) {
+			return true
+		}
+	}
+
+	return false
+}
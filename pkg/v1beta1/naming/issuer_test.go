@@ -0,0 +1,75 @@
+package naming
+
+import (
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+func TestResolveIssuer(t *testing.T) {
+	t.Parallel()
+
+	def := v1.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "cert-manager.io"}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		v1beta1labels.IssuerAnnotation:         "team-ca",
+		v1beta1labels.AllowedIssuersAnnotation: "letsencrypt",
+	}}}
+	gateway := func(annotations map[string]string) *istiov1.Gateway {
+		return &istiov1.Gateway{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	got, rejected := ResolveIssuer("default", nil, gateway(nil))
+	assert.Equal(t, def, got)
+	assert.False(t, rejected)
+
+	// without a namespace the allowlist cannot be checked
+	got, rejected = ResolveIssuer("default", nil, gateway(map[string]string{v1beta1labels.IssuerAnnotation: "selfsigned"}))
+	assert.Equal(t, "selfsigned", got.Name)
+	assert.False(t, rejected)
+
+	got, rejected = ResolveIssuer("default", ns, gateway(nil))
+	assert.Equal(t, "team-ca", got.Name)
+	assert.False(t, rejected)
+
+	got, rejected = ResolveIssuer("default", ns, gateway(map[string]string{v1beta1labels.IssuerAnnotation: "letsencrypt"}))
+	assert.Equal(t, "letsencrypt", got.Name)
+	assert.False(t, rejected)
+
+	got, rejected = ResolveIssuer("default", ns, gateway(map[string]string{v1beta1labels.IssuerAnnotation: "selfsigned"}))
+	assert.Equal(t, "team-ca", got.Name)
+	assert.True(t, rejected)
+}
+
+func TestIssuerAllowed(t *testing.T) {
+	t.Parallel()
+
+	clusterIssuer := v1.ObjectReference{Name: "letsencrypt", Kind: "ClusterIssuer", Group: "cert-manager.io"}
+	stepIssuer := v1.ObjectReference{Name: "letsencrypt", Kind: "StepClusterIssuer", Group: "certmanager.step.sm"}
+
+	tests := []struct {
+		description string
+		ref         v1.ObjectReference
+		allowed     []string
+		want        bool
+	}{
+		{description: "name", ref: clusterIssuer, allowed: []string{"letsencrypt"}, want: true},
+		{description: "kind and name", ref: clusterIssuer, allowed: []string{"clusterissuer/letsencrypt"}, want: true},
+		{description: "kind, group and name", ref: clusterIssuer, allowed: []string{"ClusterIssuer.cert-manager.io/letsencrypt"}, want: true},
+		{description: "empty group is cert-manager", ref: v1.ObjectReference{Name: "letsencrypt", Kind: "ClusterIssuer"}, allowed: []string{"letsencrypt"}, want: true},
+		{description: "other name", ref: clusterIssuer, allowed: []string{"selfsigned"}},
+		{description: "other kind", ref: clusterIssuer, allowed: []string{"Issuer/letsencrypt"}},
+		{description: "name of another group", ref: stepIssuer, allowed: []string{"letsencrypt", "StepClusterIssuer/letsencrypt"}},
+		{description: "other group", ref: clusterIssuer, allowed: []string{"ClusterIssuer.certmanager.step.sm/letsencrypt"}},
+		{description: "external issuer", ref: stepIssuer, allowed: []string{"StepClusterIssuer.certmanager.step.sm/letsencrypt"}, want: true},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, IssuerAllowed(test.ref, test.allowed), test.description)
+	}
+}
@@ -0,0 +1,122 @@
+// Package naming holds the credentialName naming rules shared by the admission webhook, which names the
+// credentialName of each server, and the controllers, which manage the Certificates of those names.
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+// Granularity selects how the TLS servers, or listeners, of a Gateway are grouped into Certificates.  The
+// admission webhook names the credentialName of each server after its group and the controllers manage one
+// Certificate per distinct credentialName, holding the hosts of every server that references it.
+type Granularity string
+
+const (
+	// GranularityServer creates one Certificate per TLS server, named after the server port name.
+	GranularityServer Granularity = "server"
+	// GranularityGateway creates one Certificate for all TLS servers of a Gateway.
+	GranularityGateway Granularity = "gateway"
+	// GranularityHost creates one Certificate per host, named after the host.  Istio serves a single
+	// credential per server, so only servers with a single host are named after their host.
+	GranularityHost Granularity = "host"
+	// GranularityShared creates one Certificate per distinct set of hosts and issuer, shared by every Gateway
+	// serving them.  Only SIMPLE servers are shared, other servers are named after their port name.
+	GranularityShared Granularity = "shared"
+
+	sharedCredentialNamePrefix = "shared-"
+	sharedCredentialHashLength = 20
+
+	// SecretNameMaxLength is the maximum length of a Secret, and Certificate, name.
+	SecretNameMaxLength = 253
+	// ClientCASecretSuffix names the Secret Istio reads the client CA of a mutual TLS server from,
+	// "<credentialName>-cacert", before falling back to the ca.crt key of the credentialName Secret.
+	ClientCASecretSuffix = "-cacert"
+	// CredentialNameMaxLength is the maximum length of a generated credentialName, leaving room for the
+	// ClientCASecretSuffix.
+	CredentialNameMaxLength = SecretNameMaxLength - len(ClientCASecretSuffix)
+	truncationHashLength    = 8
+)
+
+// ParseGranularity returns the Granularity named by s.
+func ParseGranularity(s string) (Granularity, error) {
+	switch g := Granularity(strings.ToLower(strings.TrimSpace(s))); g {
+	case GranularityServer, GranularityGateway, GranularityHost, GranularityShared:
+		return g, nil
+	default:
+		return "", fmt.Errorf("invalid certificate granularity %q, must be one of: %s, %s, %s, %s", s, GranularityServer, GranularityGateway, GranularityHost, GranularityShared)
+	}
+}
+
+// GranularityFor returns the Granularity requested by the Gateway annotation, or def when the annotation is
+// missing or invalid.
+func GranularityFor(gateway client.Object, def Granularity) Granularity {
+	v, ok := gateway.GetAnnotations()[v1beta1labels.GranularityAnnotation]
+	if !ok {
+		return def
+	}
+
+	g, err := ParseGranularity(v)
+	if err != nil {
+		return def
+	}
+
+	return g
+}
+
+// TruncateWithHash truncates s to max characters, replacing its end with a short hash of key, e.g. the untruncated
+// Gateway, so names only differing beyond the limit are not truncated to the same name.
+func TruncateWithHash(s string, max int, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])[:truncationHashLength]
+	if max <= len(hash)+1 {
+		return s[:max]
+	}
+
+	// Secret names are DNS subdomains, the truncated part must not end with a separator
+	return fmt.Sprintf("%s-%s", strings.TrimRight(s[:max-len(hash)-1], "-."), hash)
+}
+
+// SharedCredentialName returns the content addressed credentialName of a shared Certificate for the hosts, a
+// hash of the normalized hosts and the resolved issuer, see ResolveIssuer, so Gateways serving the same hosts from
+// the same issuer share a Certificate.  False is returned when none of the hosts can be issued.
+func SharedCredentialName(hosts []string, issuer v1.ObjectReference) (string, bool) {
+	normalized := NormalizeHosts(hosts, true)
+	if normalized.Len() == 0 {
+		return "", false
+	}
+
+	if issuer.Group == "" {
+		issuer.Group = DefaultIssuerGroup
+	}
+
+	h := sha256.New()
+	for _, v := range [][]string{
+		normalized.DNSNames,
+		normalized.IPAddresses,
+		{issuer.Name, issuer.Kind, issuer.Group},
+	} {
+		fmt.Fprintf(h, "%s\n", strings.Join(v, ","))
+	}
+
+	return sharedCredentialNamePrefix + hex.EncodeToString(h.Sum(nil))[:sharedCredentialHashLength], true
+}
+
+// IsSharedCredentialName reports whether the credentialName has the form of a SharedCredentialName.  The name is
+// fixed at admission, so a shared server stays shared when the issuer it would resolve to changes afterwards.
+func IsSharedCredentialName(name string) bool {
+	hash, ok := strings.CutPrefix(name, sharedCredentialNamePrefix)
+	if !ok || len(hash) != sharedCredentialHashLength {
+		return false
+	}
+
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
@@ -0,0 +1,92 @@
+package naming
+
+import (
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
+
+	v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+)
+
+func TestParseGranularity(t *testing.T) {
+	t.Parallel()
+
+	for _, v := range []string{"server", "gateway", "host", "shared", " Host "} {
+		_, err := ParseGranularity(v)
+		assert.NoError(t, err, v)
+	}
+
+	_, err := ParseGranularity("listener")
+	assert.Error(t, err)
+}
+
+func TestGranularityFor(t *testing.T) {
+	t.Parallel()
+
+	gateway := &istiov1.Gateway{}
+	assert.Equal(t, GranularityServer, GranularityFor(gateway, GranularityServer))
+
+	gateway.Annotations = map[string]string{v1beta1labels.GranularityAnnotation: "host"}
+	assert.Equal(t, GranularityHost, GranularityFor(gateway, GranularityServer))
+
+	gateway.Annotations = map[string]string{v1beta1labels.GranularityAnnotation: "invalid"}
+	assert.Equal(t, GranularityGateway, GranularityFor(gateway, GranularityGateway))
+}
+
+func TestTruncateWithHash(t *testing.T) {
+	t.Parallel()
+
+	n := TruncateWithHash("a-b-c-d", 6, "key")
+	assert.Len(t, n, 6)
+
+	// separators are not left before the hash
+	n = TruncateWithHash("ab.-cdefghijkl", 12, "key")
+	assert.Regexp(t, "^ab-[0-9a-f]{8}$", n)
+
+	assert.Equal(t, "abc", TruncateWithHash("abcdef", 3, "key"))
+}
+
+func TestSharedCredentialName(t *testing.T) {
+	t.Parallel()
+
+	issuer := v1.ObjectReference{Name: "default", Kind: "ClusterIssuer", Group: "cert-manager.io"}
+	name, ok := SharedCredentialName([]string{"b.example.com", "a.example.com"}, issuer)
+	assert.True(t, ok)
+	assert.Regexp(t, "^shared-[0-9a-f]{20}$", name)
+
+	// normalized hosts give the same name
+	same, _ := SharedCredentialName([]string{"default/A.example.com", "b.example.com.", "a.example.com"}, issuer)
+	assert.Equal(t, name, same)
+
+	// an empty group is the cert-manager group
+	same, _ = SharedCredentialName([]string{"a.example.com", "b.example.com"}, v1.ObjectReference{Name: "default", Kind: "ClusterIssuer"})
+	assert.Equal(t, name, same)
+
+	other, _ := SharedCredentialName([]string{"a.example.com"}, issuer)
+	assert.NotEqual(t, name, other)
+
+	for _, ref := range []v1.ObjectReference{
+		{Name: "other", Kind: "ClusterIssuer", Group: "cert-manager.io"},
+		{Name: "default", Kind: "Issuer", Group: "cert-manager.io"},
+		{Name: "default", Kind: "ClusterIssuer", Group: "certmanager.step.sm"},
+	} {
+		other, _ = SharedCredentialName([]string{"a.example.com", "b.example.com"}, ref)
+		assert.NotEqual(t, name, other, ref)
+	}
+
+	_, ok = SharedCredentialName([]string{"*"}, issuer)
+	assert.False(t, ok)
+}
+
+func TestIsSharedCredentialName(t *testing.T) {
+	t.Parallel()
+
+	name, _ := SharedCredentialName([]string{"a.example.com"}, v1.ObjectReference{Name: "default", Kind: "ClusterIssuer"})
+	assert.True(t, IsSharedCredentialName(name))
+
+	for _, n := range []string{"", "shared", "shared-", "shared-0123456789abcdef012", "shared-0123456789abcdef012x", "https-0123456789abcdef0123"} {
+		assert.False(t, IsSharedCredentialName(n), n)
+	}
+}
@@ -0,0 +1,54 @@
+package naming
+
+import (
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	apinetworkingv1 "istio.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// IsManagedTLSMode reports whether Istio servers with the TLS mode are given a credentialName and Certificate.
+func IsManagedTLSMode(mode apinetworkingv1.ServerTLSSettings_TLSmode) bool {
+	return mode == apinetworkingv1.ServerTLSSettings_SIMPLE || IsMutualTLSMode(mode)
+}
+
+// IsMutualTLSMode reports whether Istio servers with the TLS mode verify client certificates against a CA bundle.
+func IsMutualTLSMode(mode apinetworkingv1.ServerTLSSettings_TLSmode) bool {
+	return mode == apinetworkingv1.ServerTLSSettings_MUTUAL || mode == apinetworkingv1.ServerTLSSettings_OPTIONAL_MUTUAL
+}
+
+// IsUnmanagedServer reports whether the UnmanagedServersAnnotation of the Gateway lists the port name, or one of
+// the hosts, of a server or listener.  Unmanaged servers keep the credentialName set by the user, e.g. for a
+// certificate bought from a vendor, and are not given a Certificate.
+func IsUnmanagedServer(gateway client.Object, portName string, hosts []string) bool {
+	v, ok := gateway.GetAnnotations()[v1beta1labels.UnmanagedServersAnnotation]
+	if !ok {
+		return false
+	}
+
+	entries := v1beta1labels.ParseUnmanagedServers(v)
+	if portName != "" && sets.New(entries...).Has(portName) {
+		return true
+	}
+
+	listed := sets.New(NormalizeHosts(entries, true).DNSNames...)
+	for _, h := range NormalizeHosts(hosts, true).DNSNames {
+		if listed.Has(h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsUnmanagedListener reports whether the UnmanagedServersAnnotation of the Gateway lists the name, or the
+// hostname, of a Gateway API listener.
+func IsUnmanagedListener(gateway client.Object, l gatewayapiv1beta1.Listener) bool {
+	hosts := []string{}
+	if l.Hostname != nil {
+		hosts = append(hosts, string(*l.Hostname))
+	}
+
+	return IsUnmanagedServer(gateway, string(l.Name), hosts)
+}
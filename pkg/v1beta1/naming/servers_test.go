@@ -0,0 +1,51 @@
+package naming
+
+import (
+	"testing"
+
+	v1beta1labels "github.com/kanopy-platform/gateway-certificate-controller/pkg/v1beta1/labels"
+	"github.com/stretchr/testify/assert"
+	networkingv1 "istio.io/api/networking/v1"
+	istiov1 "istio.io/client-go/pkg/apis/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayapiv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+func TestIsManagedTLSMode(t *testing.T) {
+	t.Parallel()
+	assert.True(t, IsManagedTLSMode(networkingv1.ServerTLSSettings_SIMPLE))
+	assert.True(t, IsManagedTLSMode(networkingv1.ServerTLSSettings_MUTUAL))
+	assert.True(t, IsManagedTLSMode(networkingv1.ServerTLSSettings_OPTIONAL_MUTUAL))
+	assert.False(t, IsManagedTLSMode(networkingv1.ServerTLSSettings_PASSTHROUGH))
+	assert.False(t, IsManagedTLSMode(networkingv1.ServerTLSSettings_ISTIO_MUTUAL))
+	assert.False(t, IsMutualTLSMode(networkingv1.ServerTLSSettings_SIMPLE))
+}
+
+func TestIsUnmanagedServer(t *testing.T) {
+	t.Parallel()
+
+	gateway := &istiov1.Gateway{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		v1beta1labels.UnmanagedServersAnnotation: "https-ev, Shop.example.com",
+	}}}
+
+	tests := []struct {
+		description string
+		portName    string
+		hosts       []string
+		want        bool
+	}{
+		{description: "port name", portName: "https-ev", hosts: []string{"www.example.com"}, want: true},
+		{description: "host", portName: "https", hosts: []string{"a.example.com", "devops/shop.example.com"}, want: true},
+		{description: "not listed", portName: "https", hosts: []string{"a.example.com"}, want: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, IsUnmanagedServer(gateway, test.portName, test.hosts), test.description)
+	}
+
+	assert.False(t, IsUnmanagedServer(&istiov1.Gateway{}, "https-ev", nil))
+
+	hostname := gatewayapiv1beta1.Hostname("shop.example.com")
+	assert.True(t, IsUnmanagedListener(gateway, gatewayapiv1beta1.Listener{Name: "https", Hostname: &hostname}))
+	assert.False(t, IsUnmanagedListener(gateway, gatewayapiv1beta1.Listener{Name: "https"}))
+}